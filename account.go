@@ -1,46 +1,81 @@
 package signalmgr
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
-	"strings"
+	"time"
 
 	"github.com/DonovanDiamond/signalmgr/signaltypes"
-	"github.com/gorilla/websocket"
 )
 
 type Account struct {
 	Number string
+	// Client used for requests made by this account. If nil, DefaultClient is used.
+	Client *Client
+}
+
+func (a *Account) client() *Client {
+	if a.Client == nil {
+		return DefaultClient
+	}
+	return a.Client
 }
 
 // List all accounts
 //
 // Lists all of the accounts linked or registered
-func GetAccounts() (accounts []Account, err error) {
-	list, err := get[[]string]("/v1/accounts")
+func (c *Client) GetAccounts() (accounts []Account, err error) {
+	return c.GetAccountsCtx(context.Background())
+}
+
+// Same as GetAccounts, but uses ctx for cancellation and deadlines.
+func (c *Client) GetAccountsCtx(ctx context.Context) (accounts []Account, err error) {
+	list, err := get[[]string](ctx, c, "/v1/accounts")
 	if err != nil {
 		return
 	}
 	for _, num := range list {
 		accounts = append(accounts, Account{
 			Number: num,
+			Client: c,
 		})
 	}
 	return
 }
 
+// Calls GetAccounts on DefaultClient.
+func GetAccounts() (accounts []Account, err error) {
+	return GetAccountsCtx(context.Background())
+}
+
+// Calls GetAccountsCtx on DefaultClient.
+func GetAccountsCtx(ctx context.Context) (accounts []Account, err error) {
+	return DefaultClient.GetAccountsCtx(ctx)
+}
+
 type Account_Configuration struct {
 	TrustMode string `json:"trust_mode"`
 }
 
 // List account specific settings.
 func (a *Account) GetConfiguration() (resp Account_Configuration, err error) {
-	return get[Account_Configuration](fmt.Sprintf("/v1/configuration/%s/settings", a.Number))
+	return a.GetConfigurationCtx(context.Background())
+}
+
+// Same as GetConfiguration, but uses ctx for cancellation and deadlines.
+func (a *Account) GetConfigurationCtx(ctx context.Context) (resp Account_Configuration, err error) {
+	return get[Account_Configuration](ctx, a.client(), fmt.Sprintf("/v1/configuration/%s/settings", a.Number))
 }
 
 // Set account specific settings.
 func (a *Account) PostConfiguration(data Account_Configuration) (err error) {
-	_, err = post[any](fmt.Sprintf("/v1/configuration/%s/settings", a.Number), data)
+	return a.PostConfigurationCtx(context.Background(), data)
+}
+
+// Same as PostConfiguration, but uses ctx for cancellation and deadlines.
+func (a *Account) PostConfigurationCtx(ctx context.Context, data Account_Configuration) (err error) {
+	_, err = post[any](ctx, a.client(), fmt.Sprintf("/v1/configuration/%s/settings", a.Number), data)
 	return
 }
 
@@ -48,7 +83,14 @@ func (a *Account) PostConfiguration(data Account_Configuration) (err error) {
 func (a *Account) PostLinkDevice(data struct {
 	URI string `json:"uri"`
 }) (err error) {
-	_, err = post[any](fmt.Sprintf("/v1/devices/%s", a.Number), data)
+	return a.PostLinkDeviceCtx(context.Background(), data)
+}
+
+// Same as PostLinkDevice, but uses ctx for cancellation and deadlines.
+func (a *Account) PostLinkDeviceCtx(ctx context.Context, data struct {
+	URI string `json:"uri"`
+}) (err error) {
+	_, err = post[any](ctx, a.client(), fmt.Sprintf("/v1/devices/%s", a.Number), data)
 	return
 }
 
@@ -56,7 +98,12 @@ func (a *Account) PostLinkDevice(data struct {
 //
 // Register a phone number with the signal network.
 func (a *Account) PostRegister(captcha string, useVoice bool) error {
-	_, err := post[any](fmt.Sprintf("/v1/register/%s", a.Number), struct {
+	return a.PostRegisterCtx(context.Background(), captcha, useVoice)
+}
+
+// Same as PostRegister, but uses ctx for cancellation and deadlines.
+func (a *Account) PostRegisterCtx(ctx context.Context, captcha string, useVoice bool) error {
+	_, err := post[any](ctx, a.client(), fmt.Sprintf("/v1/register/%s", a.Number), struct {
 		Captcha  string `json:"captcha"`
 		UseVoice bool   `json:"use_voice"`
 	}{
@@ -70,7 +117,12 @@ func (a *Account) PostRegister(captcha string, useVoice bool) error {
 //
 // Verify a registered phone number with the signal network.
 func (a *Account) PostRegisterVerify(token string, pin string) error {
-	_, err := post[any](fmt.Sprintf("/v1/register/%s/verify/%s", a.Number, token), struct {
+	return a.PostRegisterVerifyCtx(context.Background(), token, pin)
+}
+
+// Same as PostRegisterVerify, but uses ctx for cancellation and deadlines.
+func (a *Account) PostRegisterVerifyCtx(ctx context.Context, token string, pin string) error {
+	_, err := post[any](ctx, a.client(), fmt.Sprintf("/v1/register/%s/verify/%s", a.Number, token), struct {
 		PIN string `json:"pin"`
 	}{
 		PIN: pin,
@@ -85,7 +137,15 @@ func (a *Account) PostUnregistert(data struct {
 	DeleteAccount   bool `json:"delete_account"`
 	DeleteLocalData bool `json:"delete_local_data"`
 }) (err error) {
-	_, err = post[any](fmt.Sprintf("/v1/unregister/%s", a.Number), data)
+	return a.PostUnregistertCtx(context.Background(), data)
+}
+
+// Same as PostUnregistert, but uses ctx for cancellation and deadlines.
+func (a *Account) PostUnregistertCtx(ctx context.Context, data struct {
+	DeleteAccount   bool `json:"delete_account"`
+	DeleteLocalData bool `json:"delete_local_data"`
+}) (err error) {
+	_, err = post[any](ctx, a.client(), fmt.Sprintf("/v1/unregister/%s", a.Number), data)
 	return
 }
 
@@ -96,7 +156,15 @@ func (a *Account) PostRateLimitChallenge(data struct {
 	Captcha        string `json:"captcha"`
 	ChallengeToken string `json:"challenge_token"`
 }) (err error) {
-	_, err = post[any](fmt.Sprintf("/v1/accounts/%s/rate-limit-challenge", a.Number), data)
+	return a.PostRateLimitChallengeCtx(context.Background(), data)
+}
+
+// Same as PostRateLimitChallenge, but uses ctx for cancellation and deadlines.
+func (a *Account) PostRateLimitChallengeCtx(ctx context.Context, data struct {
+	Captcha        string `json:"captcha"`
+	ChallengeToken string `json:"challenge_token"`
+}) (err error) {
+	_, err = post[any](ctx, a.client(), fmt.Sprintf("/v1/accounts/%s/rate-limit-challenge", a.Number), data)
 	return
 }
 
@@ -107,7 +175,15 @@ func (a *Account) PutSettings(data struct {
 	DiscoverableByNumber bool `json:"discoverable_by_number"`
 	ShareNumber          bool `json:"share_number"`
 }) (err error) {
-	_, err = put[any](fmt.Sprintf("/v1/accounts/%s/settings", a.Number), data)
+	return a.PutSettingsCtx(context.Background(), data)
+}
+
+// Same as PutSettings, but uses ctx for cancellation and deadlines.
+func (a *Account) PutSettingsCtx(ctx context.Context, data struct {
+	DiscoverableByNumber bool `json:"discoverable_by_number"`
+	ShareNumber          bool `json:"share_number"`
+}) (err error) {
+	_, err = put[any](ctx, a.client(), fmt.Sprintf("/v1/accounts/%s/settings", a.Number), data)
 	return
 }
 
@@ -122,14 +198,26 @@ type Account_PostUsernameResponse struct {
 func (a *Account) PostUsername(data struct {
 	Username string `json:"username"`
 }) (resp Account_PostUsernameResponse, err error) {
-	return post[Account_PostUsernameResponse](fmt.Sprintf("/v1/accounts/%s/username", a.Number), data)
+	return a.PostUsernameCtx(context.Background(), data)
+}
+
+// Same as PostUsername, but uses ctx for cancellation and deadlines.
+func (a *Account) PostUsernameCtx(ctx context.Context, data struct {
+	Username string `json:"username"`
+}) (resp Account_PostUsernameResponse, err error) {
+	return post[Account_PostUsernameResponse](ctx, a.client(), fmt.Sprintf("/v1/accounts/%s/username", a.Number), data)
 }
 
 // Remove a username.
 //
 // Delete the username associated with this account.
 func (a *Account) DeleteUsername() (err error) {
-	_, err = delete[any](fmt.Sprintf("/v1/accounts/%s/username", a.Number), nil)
+	return a.DeleteUsernameCtx(context.Background())
+}
+
+// Same as DeleteUsername, but uses ctx for cancellation and deadlines.
+func (a *Account) DeleteUsernameCtx(ctx context.Context) (err error) {
+	_, err = del[any](ctx, a.client(), fmt.Sprintf("/v1/accounts/%s/username", a.Number), nil)
 	return
 }
 
@@ -147,7 +235,12 @@ type Group struct {
 
 // List all Signal Groups.
 func (a *Account) GetGroups() (groups []Group, err error) {
-	return get[[]Group](fmt.Sprintf("/v1/groups/%s", a.Number))
+	return a.GetGroupsCtx(context.Background())
+}
+
+// Same as GetGroups, but uses ctx for cancellation and deadlines.
+func (a *Account) GetGroupsCtx(ctx context.Context) (groups []Group, err error) {
+	return get[[]Group](ctx, a.client(), fmt.Sprintf("/v1/groups/%s", a.Number))
 }
 
 // Create a new Signal Group with the specified members.
@@ -163,15 +256,37 @@ func (a *Account) PostCreateGroup(data struct {
 	} `json:"permissions"`
 }) (resp struct {
 	ID string `json:"id"`
+}, err error) {
+	return a.PostCreateGroupCtx(context.Background(), data)
+}
+
+// Same as PostCreateGroup, but uses ctx for cancellation and deadlines.
+func (a *Account) PostCreateGroupCtx(ctx context.Context, data struct {
+	Description    string   `json:"description"`
+	ExpirationTime int      `json:"expiration_time"`
+	GroupLink      string   `json:"group_link"`
+	Members        []string `json:"members"`
+	Name           string   `json:"name"`
+	Permissions    struct {
+		AddMembers string `json:"add_members"`
+		EditGroup  string `json:"edit_group"`
+	} `json:"permissions"`
+}) (resp struct {
+	ID string `json:"id"`
 }, err error) {
 	return post[struct {
 		ID string `json:"id"`
-	}](fmt.Sprintf("/v1/groups/%s", a.Number), data)
+	}](ctx, a.client(), fmt.Sprintf("/v1/groups/%s", a.Number), data)
 }
 
 // List a specific Signal Group.
 func (a *Account) GetGroup(groupID string) (group Group, err error) {
-	return get[Group](fmt.Sprintf("/v1/groups/%s/%s", a.Number, groupID))
+	return a.GetGroupCtx(context.Background(), groupID)
+}
+
+// Same as GetGroup, but uses ctx for cancellation and deadlines.
+func (a *Account) GetGroupCtx(ctx context.Context, groupID string) (group Group, err error) {
+	return get[Group](ctx, a.client(), fmt.Sprintf("/v1/groups/%s/%s", a.Number, groupID))
 }
 
 // Update the state of a Signal Group.
@@ -180,13 +295,27 @@ func (a *Account) PutGroupSettings(groupID string, data struct {
 	Description  string `json:"description"`
 	Name         string `json:"name"`
 }) (err error) {
-	_, err = put[any](fmt.Sprintf("/v1/groups/%s/%s", a.Number, groupID), data)
+	return a.PutGroupSettingsCtx(context.Background(), groupID, data)
+}
+
+// Same as PutGroupSettings, but uses ctx for cancellation and deadlines.
+func (a *Account) PutGroupSettingsCtx(ctx context.Context, groupID string, data struct {
+	Base64Avatar string `json:"base64_avatar"`
+	Description  string `json:"description"`
+	Name         string `json:"name"`
+}) (err error) {
+	_, err = put[any](ctx, a.client(), fmt.Sprintf("/v1/groups/%s/%s", a.Number, groupID), data)
 	return
 }
 
 // Delete the specified Signal Group.
 func (a *Account) DeleteGroup(groupID string) (err error) {
-	_, err = delete[any](fmt.Sprintf("/v1/groups/%s/%s", a.Number, groupID), nil)
+	return a.DeleteGroupCtx(context.Background(), groupID)
+}
+
+// Same as DeleteGroup, but uses ctx for cancellation and deadlines.
+func (a *Account) DeleteGroupCtx(ctx context.Context, groupID string) (err error) {
+	_, err = del[any](ctx, a.client(), fmt.Sprintf("/v1/groups/%s/%s", a.Number, groupID), nil)
 	return
 }
 
@@ -194,7 +323,14 @@ func (a *Account) DeleteGroup(groupID string) (err error) {
 func (a *Account) PostGroupAdmins(groupID string, data struct {
 	Admins []string `json:"admins"`
 }) (err error) {
-	_, err = post[any](fmt.Sprintf("/v1/groups/%s/%s/admins", a.Number, groupID), data)
+	return a.PostGroupAdminsCtx(context.Background(), groupID, data)
+}
+
+// Same as PostGroupAdmins, but uses ctx for cancellation and deadlines.
+func (a *Account) PostGroupAdminsCtx(ctx context.Context, groupID string, data struct {
+	Admins []string `json:"admins"`
+}) (err error) {
+	_, err = post[any](ctx, a.client(), fmt.Sprintf("/v1/groups/%s/%s/admins", a.Number, groupID), data)
 	return
 }
 
@@ -202,19 +338,36 @@ func (a *Account) PostGroupAdmins(groupID string, data struct {
 func (a *Account) DeleteGroupAdmins(groupID string, data struct {
 	Admins []string `json:"admins"`
 }) (err error) {
-	_, err = delete[any](fmt.Sprintf("/v1/groups/%s/%s/admins", a.Number, groupID), data)
+	return a.DeleteGroupAdminsCtx(context.Background(), groupID, data)
+}
+
+// Same as DeleteGroupAdmins, but uses ctx for cancellation and deadlines.
+func (a *Account) DeleteGroupAdminsCtx(ctx context.Context, groupID string, data struct {
+	Admins []string `json:"admins"`
+}) (err error) {
+	_, err = del[any](ctx, a.client(), fmt.Sprintf("/v1/groups/%s/%s/admins", a.Number, groupID), data)
 	return
 }
 
 // Block the specified Signal Group.
 func (a *Account) PostBlockGroup(groupID string) (err error) {
-	_, err = post[any](fmt.Sprintf("/v1/groups/%s/%s/block", a.Number, groupID), nil)
+	return a.PostBlockGroupCtx(context.Background(), groupID)
+}
+
+// Same as PostBlockGroup, but uses ctx for cancellation and deadlines.
+func (a *Account) PostBlockGroupCtx(ctx context.Context, groupID string) (err error) {
+	_, err = post[any](ctx, a.client(), fmt.Sprintf("/v1/groups/%s/%s/block", a.Number, groupID), nil)
 	return
 }
 
 // Join the specified Signal Group.
 func (a *Account) PostJoinGroup(groupID string) (err error) {
-	_, err = post[any](fmt.Sprintf("/v1/groups/%s/%s/join", a.Number, groupID), nil)
+	return a.PostJoinGroupCtx(context.Background(), groupID)
+}
+
+// Same as PostJoinGroup, but uses ctx for cancellation and deadlines.
+func (a *Account) PostJoinGroupCtx(ctx context.Context, groupID string) (err error) {
+	_, err = post[any](ctx, a.client(), fmt.Sprintf("/v1/groups/%s/%s/join", a.Number, groupID), nil)
 	return
 }
 
@@ -222,7 +375,14 @@ func (a *Account) PostJoinGroup(groupID string) (err error) {
 func (a *Account) PostGroupMembers(groupID string, data struct {
 	Members []string `json:"members"`
 }) (err error) {
-	_, err = post[any](fmt.Sprintf("/v1/groups/%s/%s/members", a.Number, groupID), nil)
+	return a.PostGroupMembersCtx(context.Background(), groupID, data)
+}
+
+// Same as PostGroupMembers, but uses ctx for cancellation and deadlines.
+func (a *Account) PostGroupMembersCtx(ctx context.Context, groupID string, data struct {
+	Members []string `json:"members"`
+}) (err error) {
+	_, err = post[any](ctx, a.client(), fmt.Sprintf("/v1/groups/%s/%s/members", a.Number, groupID), nil)
 	return
 }
 
@@ -230,13 +390,25 @@ func (a *Account) PostGroupMembers(groupID string, data struct {
 func (a *Account) DeleteGroupMembers(groupID string, data struct {
 	Members []string `json:"members"`
 }) (err error) {
-	_, err = delete[any](fmt.Sprintf("/v1/groups/%s/%s/members", a.Number, groupID), nil)
+	return a.DeleteGroupMembersCtx(context.Background(), groupID, data)
+}
+
+// Same as DeleteGroupMembers, but uses ctx for cancellation and deadlines.
+func (a *Account) DeleteGroupMembersCtx(ctx context.Context, groupID string, data struct {
+	Members []string `json:"members"`
+}) (err error) {
+	_, err = del[any](ctx, a.client(), fmt.Sprintf("/v1/groups/%s/%s/members", a.Number, groupID), nil)
 	return
 }
 
 // Quit the specified Signal Group.
 func (a *Account) PostQuitGroup(groupID string) (err error) {
-	_, err = post[any](fmt.Sprintf("/v1/groups/%s/%s/quit", a.Number, groupID), nil)
+	return a.PostQuitGroupCtx(context.Background(), groupID)
+}
+
+// Same as PostQuitGroup, but uses ctx for cancellation and deadlines.
+func (a *Account) PostQuitGroupCtx(ctx context.Context, groupID string) (err error) {
+	_, err = post[any](ctx, a.client(), fmt.Sprintf("/v1/groups/%s/%s/quit", a.Number, groupID), nil)
 	return
 }
 
@@ -244,13 +416,27 @@ type MessageResponse struct {
 	Envelope  signaltypes.MessageEnvelope `json:"envelope"`
 	Account   string                      `json:"account"`
 	RawFields map[string]any              `json:"raw_fields"`
+
+	// Client the message was received with, used to reply.
+	client *Client
+	// Context with the span the message was received in, if traced.
+	traceCtx context.Context
 }
 
 // Receive Signal Messages.
 //
 // Only works if the signal api is running in `normal` or `native` mode. If you are running in `json-rpc` mode, use `GetMessagesSocket`.
 func (a *Account) GetMessages() (messages []MessageResponse, err error) {
-	return get[[]MessageResponse](fmt.Sprintf("/v1/receive/%s", a.Number))
+	return a.GetMessagesCtx(context.Background())
+}
+
+// Same as GetMessages, but uses ctx for cancellation and deadlines.
+func (a *Account) GetMessagesCtx(ctx context.Context) (messages []MessageResponse, err error) {
+	messages, err = get[[]MessageResponse](ctx, a.client(), fmt.Sprintf("/v1/receive/%s", a.Number))
+	for i := range messages {
+		messages[i].client = a.client()
+	}
+	return
 }
 
 // Opens a socket to receive Signal Messages and sends them to the `messages` channel.
@@ -259,41 +445,98 @@ func (a *Account) GetMessages() (messages []MessageResponse, err error) {
 //
 // Only works if the signal api is running in `json-rpc` mode. If you are running in `normal` or `native` mode, use `GetMessages`.
 func (a *Account) GetMessagesSocket(messages chan<- MessageResponse) (err error) {
-	baseURL := strings.ReplaceAll(API_URL, "https://", "wss://")
-	baseURL = strings.ReplaceAll(baseURL, "http://", "ws://")
-	fullURL := fmt.Sprintf("%s/v1/receive/%s", baseURL, a.Number)
+	return a.GetMessagesSocketCtx(context.Background(), messages)
+}
 
-	c, _, err := websocket.DefaultDialer.Dial(fullURL, nil)
+// Same as GetMessagesSocket, but uses ctx for cancellation and deadlines.
+//
+// The socket is closed and ctx.Err() is returned once ctx is done.
+func (a *Account) GetMessagesSocketCtx(ctx context.Context, messages chan<- MessageResponse) (err error) {
+	c, err := a.client().dial(ctx, fmt.Sprintf("/v1/receive/%s", a.Number))
 	if err != nil {
 		return fmt.Errorf("failed to dial websocket: %w", err)
 	}
+	return readSocket(ctx, a.client(), c, messages, 0)
+}
 
+// Reads messages from the socket c opened by client and sends them to the `messages` channel, closing c when it returns.
+//
+// If readTimeout is set, each read fails when nothing arrives in time.
+//
+// Will only return if there is an error, the socket closes or ctx is done.
+func readSocket(ctx context.Context, client *Client, c Conn, messages chan<- MessageResponse, readTimeout time.Duration) error {
 	defer c.Close()
 
+	// Closing the connection unblocks ReadMessage when ctx is done.
+	stop := context.AfterFunc(ctx, func() { c.Close() })
+	defer stop()
+
+	send := client.receiveHandler(func(ctx context.Context, m MessageResponse) error {
+		select {
+		case messages <- m:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
 	for {
-		// TODO: Probably not the best to unmarshal this 2 times, but I don't currently know a way around this to get the raw json
-		var all = make(map[string]any)
-		if err := c.ReadJSON(&all); err != nil {
-			return fmt.Errorf("error reading from websocket: %w", err)
+		if readTimeout > 0 {
+			c.SetReadDeadline(time.Now().Add(readTimeout))
 		}
-		raw, err := json.Marshal(all)
+		_, data, err := c.ReadMessage()
 		if err != nil {
-			return fmt.Errorf("failed to marshal message from websocket: %w", err)
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return fmt.Errorf("error reading from websocket: %w", err)
 		}
-		var m MessageResponse
-		if err := json.Unmarshal(raw, &m); err != nil {
-			return fmt.Errorf("failed to unmarshal message from websocket: %w", err)
+		if err := receiveFrame(ctx, client, data, send); err != nil {
+			return err
 		}
-		m.RawFields = all
-		messages <- m
 	}
 }
 
+// Decodes a message read from a receive websocket of client and passes it to send, in a span if client has a Tracer.
+func receiveFrame(ctx context.Context, client *Client, data []byte, send HandlerFunc) (err error) {
+	var span Span
+	if client.Tracer != nil {
+		ctx, span = client.Tracer.Start(ctx, "signal receive")
+		defer func() {
+			if err != nil && ctx.Err() == nil {
+				span.RecordError(err)
+			}
+			span.End()
+		}()
+	}
+	var all = make(map[string]any)
+	if err := json.Unmarshal(data, &all); err != nil {
+		return fmt.Errorf("failed to unmarshal message from websocket: %w", err)
+	}
+	var m MessageResponse
+	if err := json.Unmarshal(data, &m); err != nil {
+		return fmt.Errorf("failed to unmarshal message from websocket: %w", err)
+	}
+	m.RawFields = all
+	m.client = client
+	if span != nil {
+		m.traceCtx = ctx
+		setMessageAttributes(span, m)
+	}
+	return send(ctx, m)
+}
+
 // Show Typing Indicator.
 func (a *Account) PutTypingIndicator(data struct {
 	Recipient string `json:"recipient"`
 }) (err error) {
-	_, err = put[any](fmt.Sprintf("/v1/typing-indicator/%s", a.Number), data)
+	return a.PutTypingIndicatorCtx(context.Background(), data)
+}
+
+// Same as PutTypingIndicator, but uses ctx for cancellation and deadlines.
+func (a *Account) PutTypingIndicatorCtx(ctx context.Context, data struct {
+	Recipient string `json:"recipient"`
+}) (err error) {
+	_, err = put[any](ctx, a.client(), fmt.Sprintf("/v1/typing-indicator/%s", a.Number), data)
 	return
 }
 
@@ -301,7 +544,14 @@ func (a *Account) PutTypingIndicator(data struct {
 func (a *Account) DeleteTypingIndicator(data struct {
 	Recipient string `json:"recipient"`
 }) (err error) {
-	_, err = delete[any](fmt.Sprintf("/v1/typing-indicator/%s", a.Number), data)
+	return a.DeleteTypingIndicatorCtx(context.Background(), data)
+}
+
+// Same as DeleteTypingIndicator, but uses ctx for cancellation and deadlines.
+func (a *Account) DeleteTypingIndicatorCtx(ctx context.Context, data struct {
+	Recipient string `json:"recipient"`
+}) (err error) {
+	_, err = del[any](ctx, a.client(), fmt.Sprintf("/v1/typing-indicator/%s", a.Number), data)
 	return
 }
 
@@ -313,7 +563,16 @@ func (a *Account) PostProfile(data struct {
 	Base64Avatar string `json:"base64_avatar"`
 	Name         string `json:"name"`
 }) (err error) {
-	_, err = put[any](fmt.Sprintf("/v1/profiles/%s", a.Number), data)
+	return a.PostProfileCtx(context.Background(), data)
+}
+
+// Same as PostProfile, but uses ctx for cancellation and deadlines.
+func (a *Account) PostProfileCtx(ctx context.Context, data struct {
+	About        string `json:"about"`
+	Base64Avatar string `json:"base64_avatar"`
+	Name         string `json:"name"`
+}) (err error) {
+	_, err = put[any](ctx, a.client(), fmt.Sprintf("/v1/profiles/%s", a.Number), data)
 	return
 }
 
@@ -327,7 +586,12 @@ type Identity struct {
 
 // List all identities for the given number.
 func (a *Account) GetIdentities() (identities []Identity, err error) {
-	return get[[]Identity](fmt.Sprintf("/v1/identities/%s", a.Number))
+	return a.GetIdentitiesCtx(context.Background())
+}
+
+// Same as GetIdentities, but uses ctx for cancellation and deadlines.
+func (a *Account) GetIdentitiesCtx(ctx context.Context) (identities []Identity, err error) {
+	return get[[]Identity](ctx, a.client(), fmt.Sprintf("/v1/identities/%s", a.Number))
 }
 
 // Trust an identity. When 'trust_all_known_keys' is set to 'true', all known keys of this user are trusted. **This is only recommended for testing.**
@@ -335,7 +599,15 @@ func (a *Account) PutTrustIdentity(numberToTrust string, data struct {
 	TrustAllKnownKeys    bool   `json:"trust_all_known_keys"`
 	VerifiedSafetyNumber string `json:"verified_safety_number"`
 }) (err error) {
-	_, err = put[any](fmt.Sprintf("/v1/identities/%s/trust/%s", a.Number, numberToTrust), data)
+	return a.PutTrustIdentityCtx(context.Background(), numberToTrust, data)
+}
+
+// Same as PutTrustIdentity, but uses ctx for cancellation and deadlines.
+func (a *Account) PutTrustIdentityCtx(ctx context.Context, numberToTrust string, data struct {
+	TrustAllKnownKeys    bool   `json:"trust_all_known_keys"`
+	VerifiedSafetyNumber string `json:"verified_safety_number"`
+}) (err error) {
+	_, err = put[any](ctx, a.client(), fmt.Sprintf("/v1/identities/%s/trust/%s", a.Number, numberToTrust), data)
 	return
 }
 
@@ -348,7 +620,17 @@ func (a *Account) PostReaction(data struct {
 	TargetAuthor string `json:"target_author"`
 	Timestamp    int64  `json:"timestamp"`
 }) (err error) {
-	_, err = post[any](fmt.Sprintf("/v1/reactions/%s", a.Number), data)
+	return a.PostReactionCtx(context.Background(), data)
+}
+
+// Same as PostReaction, but uses ctx for cancellation and deadlines.
+func (a *Account) PostReactionCtx(ctx context.Context, data struct {
+	Reaction     string `json:"reaction"`
+	Recipient    string `json:"recipient"`
+	TargetAuthor string `json:"target_author"`
+	Timestamp    int64  `json:"timestamp"`
+}) (err error) {
+	_, err = post[any](ctx, a.client(), fmt.Sprintf("/v1/reactions/%s", a.Number), data)
 	return
 }
 
@@ -359,7 +641,36 @@ func (a *Account) DeleteReaction(data struct {
 	TargetAuthor string `json:"target_author"`
 	Timestamp    int64  `json:"timestamp"`
 }) (err error) {
-	_, err = delete[any](fmt.Sprintf("/v1/reactions/%s", a.Number), data)
+	return a.DeleteReactionCtx(context.Background(), data)
+}
+
+// Same as DeleteReaction, but uses ctx for cancellation and deadlines.
+func (a *Account) DeleteReactionCtx(ctx context.Context, data struct {
+	Reaction     string `json:"reaction"`
+	Recipient    string `json:"recipient"`
+	TargetAuthor string `json:"target_author"`
+	Timestamp    int64  `json:"timestamp"`
+}) (err error) {
+	_, err = del[any](ctx, a.client(), fmt.Sprintf("/v1/reactions/%s", a.Number), data)
+	return
+}
+
+// Delete a message.
+//
+// Delete a message we sent at timestamp for everyone in the conversation with recipient, a number, UUID or group in `group.` form.
+func (a *Account) DeleteMessage(recipient string, timestamp int64) (err error) {
+	return a.DeleteMessageCtx(context.Background(), recipient, timestamp)
+}
+
+// Same as DeleteMessage, but uses ctx for cancellation and deadlines.
+func (a *Account) DeleteMessageCtx(ctx context.Context, recipient string, timestamp int64) (err error) {
+	_, err = del[any](ctx, a.client(), fmt.Sprintf("/v1/remote-delete/%s", a.Number), struct {
+		Recipient string `json:"recipient"`
+		Timestamp int64  `json:"timestamp"`
+	}{
+		Recipient: recipient,
+		Timestamp: timestamp,
+	})
 	return
 }
 
@@ -371,7 +682,16 @@ func (a *Account) PostReceipts(data struct {
 	Recipient   string `json:"recipient"`
 	Timestamp   int64  `json:"timestamp"`
 }) (err error) {
-	_, err = post[any](fmt.Sprintf("/v1/receipts/%s", a.Number), data)
+	return a.PostReceiptsCtx(context.Background(), data)
+}
+
+// Same as PostReceipts, but uses ctx for cancellation and deadlines.
+func (a *Account) PostReceiptsCtx(ctx context.Context, data struct {
+	ReceiptType string `json:"receipt_type"`
+	Recipient   string `json:"recipient"`
+	Timestamp   int64  `json:"timestamp"`
+}) (err error) {
+	_, err = post[any](ctx, a.client(), fmt.Sprintf("/v1/receipts/%s", a.Number), data)
 	return
 }
 
@@ -385,7 +705,12 @@ type StickerPack struct {
 
 // List Installed Sticker Packs.
 func (a *Account) GetStickerPacks() (packs []StickerPack, err error) {
-	return get[[]StickerPack](fmt.Sprintf("/v1/sticker-packs/%s", a.Number))
+	return a.GetStickerPacksCtx(context.Background())
+}
+
+// Same as GetStickerPacks, but uses ctx for cancellation and deadlines.
+func (a *Account) GetStickerPacksCtx(ctx context.Context) (packs []StickerPack, err error) {
+	return get[[]StickerPack](ctx, a.client(), fmt.Sprintf("/v1/sticker-packs/%s", a.Number))
 }
 
 // Add Sticker Pack.
@@ -395,7 +720,15 @@ func (a *Account) PostStickerPack(data struct {
 	PackID  string `json:"pack_id"`
 	PackKey string `json:"pack_key"`
 }) (err error) {
-	_, err = post[any](fmt.Sprintf("/v1/sticker-packs/%s", a.Number), data)
+	return a.PostStickerPackCtx(context.Background(), data)
+}
+
+// Same as PostStickerPack, but uses ctx for cancellation and deadlines.
+func (a *Account) PostStickerPackCtx(ctx context.Context, data struct {
+	PackID  string `json:"pack_id"`
+	PackKey string `json:"pack_key"`
+}) (err error) {
+	_, err = post[any](ctx, a.client(), fmt.Sprintf("/v1/sticker-packs/%s", a.Number), data)
 	return
 }
 
@@ -414,7 +747,12 @@ type Contact struct {
 //
 // List all contacts for the given number.
 func (a *Account) GetContacts() (contacts []Contact, err error) {
-	return get[[]Contact](fmt.Sprintf("/v1/contacts/%s", a.Number))
+	return a.GetContactsCtx(context.Background())
+}
+
+// Same as GetContacts, but uses ctx for cancellation and deadlines.
+func (a *Account) GetContactsCtx(ctx context.Context) (contacts []Contact, err error) {
+	return get[[]Contact](ctx, a.client(), fmt.Sprintf("/v1/contacts/%s", a.Number))
 }
 
 // Updates the info associated to a number on the contact list. If the contact doesn’t exist yet, it will be added.
@@ -423,12 +761,26 @@ func (a *Account) PostContact(data struct {
 	Name                string `json:"name"`
 	Recipient           string `json:"recipient"`
 }) (contacts []Contact, err error) {
-	_, err = post[any](fmt.Sprintf("/v1/contacts/%s", a.Number), data)
+	return a.PostContactCtx(context.Background(), data)
+}
+
+// Same as PostContact, but uses ctx for cancellation and deadlines.
+func (a *Account) PostContactCtx(ctx context.Context, data struct {
+	ExpirationInSeconds int    `json:"expiration_in_seconds"`
+	Name                string `json:"name"`
+	Recipient           string `json:"recipient"`
+}) (contacts []Contact, err error) {
+	_, err = post[any](ctx, a.client(), fmt.Sprintf("/v1/contacts/%s", a.Number), data)
 	return
 }
 
 // Send a synchronization message with the local contacts list to all linked devices. This command should only be used if this is the primary device.
 func (a *Account) PutContactsSync() (err error) {
-	_, err = put[any](fmt.Sprintf("/v1/contacts/%s/sync", a.Number), nil)
+	return a.PutContactsSyncCtx(context.Background())
+}
+
+// Same as PutContactsSync, but uses ctx for cancellation and deadlines.
+func (a *Account) PutContactsSyncCtx(ctx context.Context) (err error) {
+	_, err = put[any](ctx, a.client(), fmt.Sprintf("/v1/contacts/%s/sync", a.Number), nil)
 	return
 }
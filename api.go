@@ -1,19 +1,87 @@
 package signalmgr
 
 import (
+	"context"
+	"crypto/tls"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
-	"github.com/gofiber/fiber/v2"
+	"github.com/gorilla/websocket"
 )
 
+// Base URL of the signal-cli-rest-api used by DefaultClient when its URL is not set.
 var API_URL = "http://127.0.0.1:8080"
 
+// A Client talks to a single signal-cli-rest-api instance.
+//
+// The zero value is usable and behaves like DefaultClient.
+type Client struct {
+	// Base URL of the signal-cli-rest-api, e.g. "http://127.0.0.1:8080". If empty, API_URL is used.
+	URL string
+	// Headers added to every request, including the websocket dial.
+	Headers map[string]string
+	// Authenticator adding credentials to every request, including the websocket dial. If nil, requests are sent without credentials.
+	Auth Authenticator
+	// HTTP client used to send requests. If nil, a client is created on first use from TLSConfig, Proxy and UnixSocket.
+	//
+	// If set, those settings only apply to websockets, which are opened with Dialer. Configure the client's transport to match them.
+	HTTPClient *http.Client
+	// TLS configuration used for https and wss URLs. Not used for requests if HTTPClient is set.
+	TLSConfig *tls.Config
+	// Proxy used for requests and websockets, see http.Transport.Proxy. If nil, the proxy is taken from the environment. Not used for requests if HTTPClient is set.
+	Proxy func(*http.Request) (*url.URL, error)
+	// Path of a Unix domain socket the REST API listens on. If set, every connection is made to it and the host in URL is ignored. Not used for requests if HTTPClient is set.
+	UnixSocket string
+	// Timeout applied to each request. Zero means no timeout.
+	Timeout time.Duration
+	// Dialer used to open websockets. If nil, websocket.DefaultDialer is used.
+	Dialer *websocket.Dialer
+	// Policy for retrying failed requests. If nil, DefaultRetryPolicy is used.
+	Retry *RetryPolicy
+	// Transport used to send requests and open websockets. If nil, requests are sent with net/http using the settings above.
+	Transport Transport
+	// Interceptors wrapping every request, the first outermost.
+	Interceptors []Interceptor
+	// Middleware wrapping every message read from a receive websocket before it is sent to the messages channel, the first outermost.
+	ReceiveMiddleware []Middleware
+	// Tracer starting spans for requests and received messages. If nil, nothing is traced.
+	Tracer Tracer
+
+	mu          sync.Mutex
+	defaultHTTP *http.Client
+}
+
+// DefaultClient is used by the package level functions and by any Account without a Client.
+var DefaultClient = &Client{}
+
+// Creates a new Client for the signal-cli-rest-api at baseURL.
+func NewClient(baseURL string) *Client {
+	return &Client{URL: baseURL}
+}
+
+func (c *Client) baseURL() string {
+	if c.URL == "" {
+		return strings.TrimSuffix(API_URL, "/")
+	}
+	return strings.TrimSuffix(c.URL, "/")
+}
+
+// Returns an Account for number that sends its requests through this client.
+func (c *Client) Account(number string) *Account {
+	return &Account{Number: number, Client: c}
+}
+
 type errorResposne struct {
-	Error string `json:"error"`
+	Error           string   `json:"error"`
+	ChallengeTokens []string `json:"challenge_tokens"`
 }
 
 type params map[string]string
@@ -26,18 +94,18 @@ func encodeParams(params params) string {
 	return urlParams.Encode()
 }
 
-// Sends a GET request to API_URL + path.
+// Sends a GET request to the client's URL + path.
 //
 // Returns the response as raw bytes.
-func getRaw(path string) (raw []byte, err error) {
-	return completeRequest(fiber.Get(strings.TrimSuffix(API_URL, "/") + path))
+func getRaw(ctx context.Context, c *Client, path string) (raw []byte, err error) {
+	return request(ctx, c, http.MethodGet, path, nil)
 }
 
-// Sends a GET request to API_URL + path.
+// Sends a GET request to the client's URL + path.
 //
 // JSON parses the response into resp of provided type.
-func get[T any](path string) (resp T, err error) {
-	raw, err := completeRequest(fiber.Get(strings.TrimSuffix(API_URL, "/") + path))
+func get[T any](ctx context.Context, c *Client, path string) (resp T, err error) {
+	raw, err := request(ctx, c, http.MethodGet, path, nil)
 	if err != nil {
 		return
 	}
@@ -48,18 +116,15 @@ func get[T any](path string) (resp T, err error) {
 	return
 }
 
-// Sends a POST request to API_URL + path, parsing data into JSON as the body.
+// Sends a POST request to the client's URL + path, parsing data into JSON as the body.
 //
 // JSON parses the response into resp of provided type.
-func post[T any](path string, data any) (resp T, err error) {
+func post[T any](ctx context.Context, c *Client, path string, data any) (resp T, err error) {
 	body, err := json.Marshal(data)
 	if err != nil {
 		return
 	}
-	req := fiber.Post(strings.TrimSuffix(API_URL, "/") + path)
-	req.Body(body)
-	req.ContentType("application/json")
-	raw, err := completeRequest(req)
+	raw, err := request(ctx, c, http.MethodPost, path, body)
 	if err != nil {
 		return
 	}
@@ -70,18 +135,15 @@ func post[T any](path string, data any) (resp T, err error) {
 	return
 }
 
-// Sends a PUT request to API_URL + path, parsing data into JSON as the body.
+// Sends a PUT request to the client's URL + path, parsing data into JSON as the body.
 //
 // JSON parses the response into resp of provided type.
-func put[T any](path string, data any) (resp T, err error) {
+func put[T any](ctx context.Context, c *Client, path string, data any) (resp T, err error) {
 	body, err := json.Marshal(data)
 	if err != nil {
 		return
 	}
-	req := fiber.Put(strings.TrimSuffix(API_URL, "/") + path)
-	req.Body(body)
-	req.ContentType("application/json")
-	raw, err := completeRequest(req)
+	raw, err := request(ctx, c, http.MethodPut, path, body)
 	if err != nil {
 		return
 	}
@@ -92,18 +154,15 @@ func put[T any](path string, data any) (resp T, err error) {
 	return
 }
 
-// Sends a DELETE request to API_URL + path, parsing data into JSON as the body.
+// Sends a DELETE request to the client's URL + path, parsing data into JSON as the body.
 //
 // JSON parses the response into resp of provided type.
-func delete[T any](path string, data any) (resp T, err error) {
+func del[T any](ctx context.Context, c *Client, path string, data any) (resp T, err error) {
 	body, err := json.Marshal(data)
 	if err != nil {
 		return
 	}
-	req := fiber.Delete(strings.TrimSuffix(API_URL, "/") + path)
-	req.Body(body)
-	req.ContentType("application/json")
-	raw, err := completeRequest(req)
+	raw, err := request(ctx, c, http.MethodDelete, path, body)
 	if err != nil {
 		return
 	}
@@ -114,21 +173,162 @@ func delete[T any](path string, data any) (resp T, err error) {
 	return
 }
 
-func completeRequest(req *fiber.Agent) (resp []byte, err error) {
-	status, resp, errs := req.Bytes()
-	if len(errs) > 0 {
-		err = fmt.Errorf("%d errors on post request: %+v", len(errs), errs)
+// Sends a method request to the client's URL + path with body as its JSON body, if not nil.
+//
+// Failed attempts are retried according to the client's RetryPolicy.
+func request(ctx context.Context, c *Client, method, path string, body []byte) (resp []byte, err error) {
+	err = c.withRetry(ctx, method, func() error {
+		return c.authenticated(ctx, func() *Request {
+			return c.newRequest(method, path, body)
+		}, func(req *Request) (err error) {
+			resp, err = completeRequest(ctx, c, req)
+			return
+		})
+	})
+	return
+}
+
+// Sends req through the client's interceptors and transport and returns the response body, or an *APIError for error responses.
+//
+// The client's Timeout applies to the whole request.
+func completeRequest(ctx context.Context, c *Client, req *Request) (body []byte, err error) {
+	if err = ctx.Err(); err != nil {
 		return
 	}
+	reqCtx := ctx
+	if c.Timeout > 0 {
+		var cancel context.CancelFunc
+		reqCtx, cancel = context.WithTimeout(ctx, c.Timeout)
+		defer cancel()
+	}
+	resp, err := c.roundTrip(reqCtx, req, true)
+	if err == nil {
+		defer resp.Body.Close()
+		body, err = io.ReadAll(resp.Body)
+	}
+	if err != nil && ctx.Err() == nil && reqCtx.Err() != nil {
+		// Only the client's timeout passed, which is worth retrying unlike a done ctx.
+		return nil, &timeoutError{req.Method, req.Path, c.Timeout}
+	}
+	if err != nil {
+		return nil, err
+	}
+	err = checkResponse(resp.StatusCode, req.Method, req.Path, body)
+	return
+}
+
+// Returned when a client's Timeout passes before the response arrives. It matches context.DeadlineExceeded, like a passed ctx deadline does.
+type timeoutError struct {
+	method, path string
+	timeout      time.Duration
+}
+
+func (e *timeoutError) Error() string {
+	return fmt.Sprintf("%s %s: timed out after %s", e.method, e.path, e.timeout)
+}
+
+func (e *timeoutError) Unwrap() error {
+	return context.DeadlineExceeded
+}
+
+// Returns an *APIError if the response has a non 2xx status or a JSON error body.
+func checkResponse(status int, method, path string, body []byte) error {
 	var errResp errorResposne
-	if json.Unmarshal(resp, &errResp); errResp.Error != "" {
-		errResp.Error = strings.ReplaceAll(errResp.Error, "\n", "")
-		err = errors.New(errResp.Error)
-		return
+	if json.Unmarshal(body, &errResp); errResp.Error != "" || status < 200 || status > 299 {
+		return &APIError{
+			StatusCode:      status,
+			Method:          method,
+			Path:            path,
+			Message:         strings.ReplaceAll(errResp.Error, "\n", ""),
+			ChallengeTokens: errResp.ChallengeTokens,
+			Body:            body,
+		}
 	}
-	if status < 200 || status > 299 {
-		err = fmt.Errorf("status %d and response received: %s", status, resp)
+	return nil
+}
+
+// Opens a websocket to the client's URL + path through the client's transport.
+func (c *Client) dial(ctx context.Context, path string) (conn Conn, err error) {
+	err = c.authenticated(ctx, func() *Request {
+		return c.newRequest(http.MethodGet, path, nil)
+	}, func(req *Request) (err error) {
+		conn, err = c.transport().Dial(ctx, req)
 		return
-	}
+	})
 	return
 }
+
+// Sends a GET request to the client's URL + path and returns the response without reading its body.
+//
+// The caller must close the returned body. Opening the response is retried according to the client's RetryPolicy, reading the body is not.
+func getStream(ctx context.Context, c *Client, path string) (body *streamBody, err error) {
+	err = c.withRetry(ctx, http.MethodGet, func() error {
+		return c.authenticated(ctx, func() *Request {
+			req := c.newRequest(http.MethodGet, path, nil)
+			req.Stream = true
+			return req
+		}, func(req *Request) (err error) {
+			body, err = openStream(ctx, c, req)
+			return
+		})
+	})
+	return
+}
+
+// Sends req, a Stream request, and returns its body, or an *APIError for error responses.
+func openStream(ctx context.Context, c *Client, req *Request) (*streamBody, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	resp, err := c.roundTrip(ctx, req, false)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, fmt.Errorf("failed to get %s: %w", req.Path, err)
+	}
+	if status := resp.StatusCode; status < 200 || status > 299 {
+		raw, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+		resp.Body.Close()
+		return nil, checkResponse(status, req.Method, req.Path, raw)
+	}
+	return &streamBody{ctx: ctx, resp: resp}, nil
+}
+
+// A streamed response body, which stops returning data once its context is done.
+type streamBody struct {
+	ctx    context.Context
+	resp   *Response
+	closed bool
+}
+
+func (b *streamBody) Read(p []byte) (int, error) {
+	if b.closed {
+		return 0, errors.New("read on closed body")
+	}
+	if err := b.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return b.resp.Body.Read(p)
+}
+
+func (b *streamBody) Close() error {
+	if b.closed {
+		return nil
+	}
+	b.closed = true
+	return b.resp.Body.Close()
+}
+
+// Returns the Content-Type header of the response.
+func (b *streamBody) contentType() string {
+	return b.resp.Header.Get("Content-Type")
+}
+
+// Returns the Content-Length header of the response, or -1 if it is unknown.
+func (b *streamBody) contentLength() int64 {
+	if n, err := strconv.ParseInt(b.resp.Header.Get("Content-Length"), 10, 64); err == nil && n >= 0 {
+		return n
+	}
+	return -1
+}
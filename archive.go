@@ -0,0 +1,277 @@
+package signalmgr
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/DonovanDiamond/signalmgr/signaltypes"
+)
+
+// A BlobStore stores archived attachments.
+type BlobStore interface {
+	// Stores the contents of r under key, replacing any blob with the same key.
+	Put(ctx context.Context, key string, r io.Reader, info AttachmentInfo) (BlobRef, error)
+	// Opens the blob stored under key. Returns an error matching ErrNotFound if there is none.
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Removes the blob stored under key. Removing a missing blob is not an error.
+	Delete(ctx context.Context, key string) error
+}
+
+// A BlobRef refers to a blob in a BlobStore.
+type BlobRef struct {
+	// Key the blob is stored under.
+	Key string
+	// Location of the blob, e.g. a file:// URL for DirStore.
+	URI string
+	// Size of the blob in bytes.
+	Size int64
+	// MIME type of the blob.
+	ContentType string
+	// Original filename of the attachment, if known.
+	Filename string
+}
+
+// A DirStore stores blobs as files below a local directory. Keys may contain slashes to create subdirectories.
+type DirStore struct {
+	Dir string
+}
+
+// Creates a DirStore storing blobs below dir.
+func NewDirStore(dir string) *DirStore {
+	return &DirStore{Dir: dir}
+}
+
+func (s *DirStore) path(key string) (string, error) {
+	if !filepath.IsLocal(filepath.FromSlash(key)) {
+		return "", fmt.Errorf("invalid blob key %q", key)
+	}
+	return filepath.Join(s.Dir, filepath.FromSlash(key)), nil
+}
+
+func (s *DirStore) Put(ctx context.Context, key string, r io.Reader, info AttachmentInfo) (ref BlobRef, err error) {
+	path, err := s.path(key)
+	if err != nil {
+		return
+	}
+	if err = os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return
+	}
+	// Write to a temporary file first, so a failed copy never leaves a partial blob.
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return
+	}
+	defer os.Remove(tmp.Name())
+	n, err := io.Copy(tmp, r)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return
+	}
+	if err = os.Rename(tmp.Name(), path); err != nil {
+		return
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return
+	}
+	return BlobRef{
+		Key:         key,
+		URI:         (&url.URL{Scheme: "file", Path: filepath.ToSlash(abs)}).String(),
+		Size:        n,
+		ContentType: info.ContentType,
+		Filename:    info.Filename,
+	}, nil
+}
+
+func (s *DirStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("blob %q: %w", key, ErrNotFound)
+	}
+	return f, err
+}
+
+func (s *DirStore) Delete(ctx context.Context, key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// A MemoryStore keeps blobs in memory, which is useful for tests.
+type MemoryStore struct {
+	mu    sync.RWMutex
+	blobs map[string]memoryBlob
+}
+
+type memoryBlob struct {
+	data []byte
+	ref  BlobRef
+}
+
+// Creates an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{}
+}
+
+func (s *MemoryStore) Put(ctx context.Context, key string, r io.Reader, info AttachmentInfo) (BlobRef, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return BlobRef{}, err
+	}
+	ref := BlobRef{
+		Key:         key,
+		URI:         "memory:" + key,
+		Size:        int64(len(data)),
+		ContentType: info.ContentType,
+		Filename:    info.Filename,
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.blobs == nil {
+		s.blobs = map[string]memoryBlob{}
+	}
+	s.blobs[key] = memoryBlob{data: data, ref: ref}
+	return ref, nil
+}
+
+func (s *MemoryStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	blob, ok := s.blobs[key]
+	if !ok {
+		return nil, fmt.Errorf("blob %q: %w", key, ErrNotFound)
+	}
+	return io.NopCloser(bytes.NewReader(blob.data)), nil
+}
+
+func (s *MemoryStore) Delete(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.blobs, key)
+	return nil
+}
+
+// Returns references to all stored blobs.
+func (s *MemoryStore) Blobs() []BlobRef {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	refs := make([]BlobRef, 0, len(s.blobs))
+	for _, blob := range s.blobs {
+		refs = append(refs, blob.ref)
+	}
+	return refs
+}
+
+// An attachment of a received message that was stored by an Archiver.
+type ArchivedAttachment struct {
+	Attachment signaltypes.Attachment
+	Blob       BlobRef
+}
+
+// An Archiver downloads the attachments of received messages into a BlobStore and deletes them from the REST API.
+type Archiver struct {
+	// Where attachments are stored.
+	Store BlobStore
+	// Leave attachments on the REST API after storing them.
+	KeepRemote bool
+	// Returns the key an attachment is stored under. Defaults to "<account>/<message timestamp>-<attachment id>".
+	Key func(m MessageResponse, attachment signaltypes.Attachment) string
+}
+
+// Creates an Archiver storing attachments in store.
+func NewArchiver(store BlobStore) *Archiver {
+	return &Archiver{Store: store}
+}
+
+func (a *Archiver) key(m MessageResponse, attachment signaltypes.Attachment) string {
+	if a.Key != nil {
+		return a.Key(m, attachment)
+	}
+	clean := strings.NewReplacer("/", "_", "\\", "_", "..", "_")
+	return fmt.Sprintf("%s/%d-%s", clean.Replace(m.Account), m.Timestamp(), clean.Replace(attachment.Id))
+}
+
+// Returns the attachments of a received message, including those of messages sent from linked devices and edits.
+func messageAttachments(m MessageResponse) []signaltypes.Attachment {
+	e := m.Envelope
+	var attachments []signaltypes.Attachment
+	attachments = append(attachments, e.DataMessage.Attachments...)
+	attachments = append(attachments, e.SyncMessage.SentMessage.Attachments...)
+	attachments = append(attachments, e.EditMessage.DataMessage.Attachments...)
+	return attachments
+}
+
+// Stores every attachment of m and, unless KeepRemote is set, deletes it from the REST API.
+//
+// Attachments that fail are skipped and left on the REST API, the returned error joins their errors.
+func (a *Archiver) Archive(ctx context.Context, m MessageResponse) (archived []ArchivedAttachment, err error) {
+	client := m.account().client()
+	var errs []error
+	for _, attachment := range messageAttachments(m) {
+		ref, err := a.archive(ctx, client, m, attachment)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to archive attachment %s: %w", attachment.Id, err))
+			continue
+		}
+		archived = append(archived, ArchivedAttachment{Attachment: attachment, Blob: ref})
+		if !a.KeepRemote {
+			if err := client.DeleteAttachmentCtx(ctx, attachment.Id); err != nil {
+				errs = append(errs, fmt.Errorf("failed to delete archived attachment %s: %w", attachment.Id, err))
+			}
+		}
+	}
+	return archived, errors.Join(errs...)
+}
+
+func (a *Archiver) archive(ctx context.Context, client *Client, m MessageResponse, attachment signaltypes.Attachment) (BlobRef, error) {
+	body, info, err := client.OpenMessageAttachment(ctx, attachment)
+	if err != nil {
+		return BlobRef{}, err
+	}
+	defer body.Close()
+	return a.Store.Put(ctx, a.key(m, attachment), body, info)
+}
+
+// Returns a handler that archives the attachments of each message before calling handler with them.
+//
+// Messages without attachments are passed on with no archived attachments. If archiving fails, handler is still called with the attachments that succeeded and the archive error is returned alongside its own.
+func (a *Archiver) Handler(handler func(ctx context.Context, m MessageResponse, archived []ArchivedAttachment) error) HandlerFunc {
+	return func(ctx context.Context, m MessageResponse) error {
+		archived, err := a.Archive(ctx, m)
+		return errors.Join(err, handler(ctx, m, archived))
+	}
+}
+
+// Registers handler on r for text and sync sent messages with attachments, archiving the attachments first.
+func (a *Archiver) Attach(r *Router, handler func(ctx context.Context, m MessageResponse, archived []ArchivedAttachment) error) {
+	h := a.Handler(handler)
+	onlyWithAttachments := func(ctx context.Context, m MessageResponse) error {
+		if len(messageAttachments(m)) == 0 {
+			return nil
+		}
+		return h(ctx, m)
+	}
+	r.Handle(EventText, onlyWithAttachments)
+	r.Handle(EventSyncSent, onlyWithAttachments)
+	r.Handle(EventEdit, onlyWithAttachments)
+}
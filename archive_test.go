@@ -0,0 +1,154 @@
+package signalmgr_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/DonovanDiamond/signalmgr"
+	"github.com/DonovanDiamond/signalmgr/signalmgrtest"
+	"github.com/DonovanDiamond/signalmgr/signaltypes"
+)
+
+func readBlob(t *testing.T, store signalmgr.BlobStore, key string) (string, error) {
+	t.Helper()
+	r, err := store.Get(context.Background(), key)
+	if err != nil {
+		return "", err
+	}
+	defer r.Close()
+	data, err := io.ReadAll(r)
+	return string(data), err
+}
+
+func TestDirStore(t *testing.T) {
+	dir := t.TempDir()
+	store := signalmgr.NewDirStore(dir)
+	ctx := context.Background()
+
+	info := signalmgr.AttachmentInfo{ContentType: "image/jpeg", Filename: "photo.jpg"}
+	ref, err := store.Put(ctx, "account/1-photo", strings.NewReader("jpeg data"), info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ref.Key != "account/1-photo" || ref.Size != 9 || ref.ContentType != "image/jpeg" || ref.Filename != "photo.jpg" ||
+		!strings.HasPrefix(ref.URI, "file://") || !strings.HasSuffix(ref.URI, "/account/1-photo") {
+		t.Errorf("got ref %+v", ref)
+	}
+	if data, err := readBlob(t, store, "account/1-photo"); err != nil || data != "jpeg data" {
+		t.Errorf("got %q, %v", data, err)
+	}
+	if _, err := store.Put(ctx, "account/1-photo", strings.NewReader("replaced"), info); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := readBlob(t, store, "account/1-photo"); data != "replaced" {
+		t.Errorf("got %q after replacing", data)
+	}
+
+	// A failed copy leaves neither a blob nor a temporary file behind.
+	failing := io.MultiReader(strings.NewReader("partial"), iotest.ErrReader(errors.New("connection reset")))
+	if _, err := store.Put(ctx, "account/2-video", failing, info); err == nil {
+		t.Error("Put succeeded with a failing reader")
+	}
+	if _, err := readBlob(t, store, "account/2-video"); !errors.Is(err, signalmgr.ErrNotFound) {
+		t.Errorf("failed blob: got %v, want ErrNotFound", err)
+	}
+	entries, err := os.ReadDir(filepath.Join(dir, "account"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("got %d files after a failed Put, want 1", len(entries))
+	}
+
+	if err := store.Delete(ctx, "account/1-photo"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := readBlob(t, store, "account/1-photo"); !errors.Is(err, signalmgr.ErrNotFound) {
+		t.Errorf("deleted blob: got %v, want ErrNotFound", err)
+	}
+	if err := store.Delete(ctx, "account/1-photo"); err != nil {
+		t.Errorf("deleting a missing blob: %v", err)
+	}
+
+	for _, key := range []string{"../escape", "/absolute", ""} {
+		if _, err := store.Put(ctx, key, strings.NewReader("x"), info); err == nil {
+			t.Errorf("Put(%q) succeeded", key)
+		}
+		if _, err := store.Get(ctx, key); err == nil {
+			t.Errorf("Get(%q) succeeded", key)
+		}
+		if err := store.Delete(ctx, key); err == nil {
+			t.Errorf("Delete(%q) succeeded", key)
+		}
+	}
+}
+
+// Delivers a message with attachments to srv and returns it as received by the account.
+func receiveAttachments(t *testing.T, srv *signalmgrtest.Server, attachments ...signaltypes.Attachment) signalmgr.MessageResponse {
+	t.Helper()
+	e := signalmgrtest.TextEnvelope(otherNumber, "see attached")
+	e.DataMessage.Attachments = attachments
+	srv.Deliver(testNumber, e)
+	messages, err := srv.Account(testNumber).GetMessagesCtx(context.Background())
+	if err != nil || len(messages) != 1 {
+		t.Fatalf("got %d messages, %v", len(messages), err)
+	}
+	return messages[0]
+}
+
+func TestArchiver(t *testing.T) {
+	srv := signalmgrtest.NewServer(testNumber)
+	defer srv.Close()
+	ctx := context.Background()
+	srv.AddAttachment("photo", []byte("jpeg data"), "application/octet-stream")
+
+	store := signalmgr.NewMemoryStore()
+	archiver := signalmgr.NewArchiver(store)
+	m := receiveAttachments(t, srv,
+		signaltypes.Attachment{Id: "photo", ContentType: "image/jpeg", Filename: "photo.jpg"},
+		signaltypes.Attachment{Id: "gone"},
+	)
+	var handled []signalmgr.ArchivedAttachment
+	err := archiver.Handler(func(ctx context.Context, m signalmgr.MessageResponse, archived []signalmgr.ArchivedAttachment) error {
+		handled = archived
+		return nil
+	})(ctx, m)
+
+	// The missing attachment fails, the other one is still archived.
+	if !errors.Is(err, signalmgr.ErrNotFound) || !strings.Contains(err.Error(), "attachment gone") {
+		t.Errorf("got %v, want the missing attachment's error", err)
+	}
+	if len(handled) != 1 || handled[0].Attachment.Id != "photo" {
+		t.Fatalf("handled %+v", handled)
+	}
+	key := fmt.Sprintf("%s/%d-photo", testNumber, m.Timestamp())
+	if ref := handled[0].Blob; ref.Key != key || ref.ContentType != "image/jpeg" || ref.Filename != "photo.jpg" {
+		t.Errorf("got ref %+v, want key %s", ref, key)
+	}
+	if data, err := readBlob(t, store, key); err != nil || data != "jpeg data" {
+		t.Errorf("got %q, %v", data, err)
+	}
+	if _, ok := srv.Attachments()["photo"]; ok {
+		t.Error("archived attachment was not deleted from the REST API")
+	}
+
+	srv.AddAttachment("doc", []byte("pdf data"), "application/pdf")
+	archiver.KeepRemote = true
+	archiver.Key = func(m signalmgr.MessageResponse, attachment signaltypes.Attachment) string {
+		return "kept/" + attachment.Id
+	}
+	archived, err := archiver.Archive(ctx, receiveAttachments(t, srv, signaltypes.Attachment{Id: "doc"}))
+	if err != nil || len(archived) != 1 || archived[0].Blob.Key != "kept/doc" {
+		t.Fatalf("got %+v, %v", archived, err)
+	}
+	if _, ok := srv.Attachments()["doc"]; !ok {
+		t.Error("KeepRemote attachment was deleted from the REST API")
+	}
+}
@@ -0,0 +1,199 @@
+package signalmgr
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/DonovanDiamond/signalmgr/signaltypes"
+)
+
+// Largest attachment Signal accepts, in bytes.
+var MaxAttachmentSize int64 = 100 << 20
+
+// Returned, wrapped with the file's name and size, when an attachment is larger than MaxAttachmentSize.
+var ErrAttachmentTooLarge = errors.New("attachment too large")
+
+func attachmentTooLarge(filename string, size int64) error {
+	return fmt.Errorf("%w: %s is %d bytes, the limit is %d bytes", ErrAttachmentTooLarge, cmp.Or(filename, "attachment"), size, MaxAttachmentSize)
+}
+
+// Encodes data as an entry for SendMessageV2.Base64Attachments, formatted as `data:<MIME-TYPE>;filename=<FILENAME>;base64,<BASE64 ENCODED DATA>`.
+//
+// If mimeType is empty it is guessed from the filename's extension, or else from the data. filename may be empty.
+func EncodeAttachment(data []byte, filename, mimeType string) (string, error) {
+	if int64(len(data)) > MaxAttachmentSize {
+		return "", attachmentTooLarge(filename, int64(len(data)))
+	}
+	if mimeType == "" {
+		mimeType = mime.TypeByExtension(filepath.Ext(filename))
+	}
+	if mimeType == "" {
+		mimeType = http.DetectContentType(data)
+	}
+	// Parameters like "; charset=utf-8" would break the entry's format.
+	if mediaType, _, err := mime.ParseMediaType(mimeType); err == nil {
+		mimeType = mediaType
+	}
+
+	var sb strings.Builder
+	sb.WriteString("data:")
+	sb.WriteString(mimeType)
+	if filename != "" {
+		sb.WriteString(";filename=")
+		sb.WriteString(strings.NewReplacer(";", "_", ",", "_").Replace(filepath.Base(filename)))
+	}
+	sb.WriteString(";base64,")
+	sb.WriteString(base64.StdEncoding.EncodeToString(data))
+	return sb.String(), nil
+}
+
+// Same as EncodeAttachment, but reads the data from r. Fails without reading everything if r holds more than MaxAttachmentSize bytes.
+func EncodeAttachmentReader(r io.Reader, filename, mimeType string) (string, error) {
+	var buf bytes.Buffer
+	n, err := buf.ReadFrom(io.LimitReader(r, MaxAttachmentSize+1))
+	if err != nil {
+		return "", fmt.Errorf("failed to read attachment: %w", err)
+	}
+	if n > MaxAttachmentSize {
+		return "", fmt.Errorf("%w: %s is over %d bytes", ErrAttachmentTooLarge, cmp.Or(filename, "attachment"), MaxAttachmentSize)
+	}
+	return EncodeAttachment(buf.Bytes(), filename, mimeType)
+}
+
+// Same as EncodeAttachment, but reads the file at path, using its name as the filename.
+func EncodeAttachmentFile(path string) (string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read attachment: %w", err)
+	}
+	if info.Size() > MaxAttachmentSize {
+		return "", attachmentTooLarge(filepath.Base(path), info.Size())
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read attachment: %w", err)
+	}
+	return EncodeAttachment(data, filepath.Base(path), "")
+}
+
+// Attaches data with the given filename and MIME type, see EncodeAttachment.
+func (b *MessageBuilder) AttachBytes(data []byte, filename, mimeType string) *MessageBuilder {
+	return b.attach(EncodeAttachment(data, filename, mimeType))
+}
+
+// Attaches the contents of r with the given filename and MIME type, see EncodeAttachmentReader.
+func (b *MessageBuilder) AttachReader(r io.Reader, filename, mimeType string) *MessageBuilder {
+	return b.attach(EncodeAttachmentReader(r, filename, mimeType))
+}
+
+// Attaches the file at path, see EncodeAttachmentFile.
+func (b *MessageBuilder) AttachFile(path string) *MessageBuilder {
+	return b.attach(EncodeAttachmentFile(path))
+}
+
+func (b *MessageBuilder) attach(entry string, err error) *MessageBuilder {
+	if err != nil {
+		b.errs = append(b.errs, err)
+		return b
+	}
+	return b.Attach(entry)
+}
+
+// Describes a downloaded attachment.
+type AttachmentInfo struct {
+	// Id of the attachment on the REST API.
+	ID string
+	// MIME type of the attachment.
+	ContentType string
+	// Size of the attachment in bytes, or -1 if it is unknown.
+	Size int64
+	// Original filename of the attachment, if known.
+	Filename string
+}
+
+// Opens the attachment with the given id for reading without loading it into memory.
+//
+// The caller must close the returned reader.
+func (c *Client) OpenAttachment(ctx context.Context, id string) (body io.ReadCloser, info AttachmentInfo, err error) {
+	stream, err := getStream(ctx, c, fmt.Sprintf("/v1/attachments/%s", id))
+	if err != nil {
+		return
+	}
+	info = AttachmentInfo{
+		ID:          id,
+		ContentType: stream.contentType(),
+		Size:        stream.contentLength(),
+	}
+	return stream, info, nil
+}
+
+// Calls OpenAttachment on DefaultClient.
+func OpenAttachment(ctx context.Context, id string) (body io.ReadCloser, info AttachmentInfo, err error) {
+	return DefaultClient.OpenAttachment(ctx, id)
+}
+
+// Streams the attachment with the given id to w without loading it into memory.
+func (c *Client) DownloadAttachment(ctx context.Context, id string, w io.Writer) (info AttachmentInfo, err error) {
+	body, info, err := c.OpenAttachment(ctx, id)
+	if err != nil {
+		return
+	}
+	defer body.Close()
+	n, err := io.Copy(w, body)
+	if err != nil {
+		return info, fmt.Errorf("failed to download attachment %s: %w", id, err)
+	}
+	info.Size = n
+	return
+}
+
+// Calls DownloadAttachment on DefaultClient.
+func DownloadAttachment(ctx context.Context, id string, w io.Writer) (info AttachmentInfo, err error) {
+	return DefaultClient.DownloadAttachment(ctx, id, w)
+}
+
+// Same as OpenAttachment, but for an attachment of a received message. The content type sent with the message takes precedence over the one served by the REST API.
+func (c *Client) OpenMessageAttachment(ctx context.Context, attachment signaltypes.Attachment) (body io.ReadCloser, info AttachmentInfo, err error) {
+	body, info, err = c.OpenAttachment(ctx, attachment.Id)
+	if err != nil {
+		return
+	}
+	info.Filename = attachment.Filename
+	if attachment.ContentType != "" {
+		info.ContentType = attachment.ContentType
+	}
+	if info.Size < 0 && attachment.Size > 0 {
+		info.Size = attachment.Size
+	}
+	return
+}
+
+// Same as DownloadAttachment, but for an attachment of a received message. The content type sent with the message takes precedence over the one served by the REST API.
+func (c *Client) DownloadMessageAttachment(ctx context.Context, attachment signaltypes.Attachment, w io.Writer) (info AttachmentInfo, err error) {
+	body, info, err := c.OpenMessageAttachment(ctx, attachment)
+	if err != nil {
+		return
+	}
+	defer body.Close()
+	n, err := io.Copy(w, body)
+	if err != nil {
+		return info, fmt.Errorf("failed to download attachment %s: %w", attachment.Id, err)
+	}
+	info.Size = n
+	return
+}
+
+// Streams the attachment of the message to w, using the client the message was received with.
+func (m MessageResponse) DownloadAttachment(ctx context.Context, attachment signaltypes.Attachment, w io.Writer) (info AttachmentInfo, err error) {
+	return m.account().client().DownloadMessageAttachment(ctx, attachment, w)
+}
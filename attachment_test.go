@@ -0,0 +1,94 @@
+package signalmgr_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/DonovanDiamond/signalmgr"
+	"github.com/DonovanDiamond/signalmgr/signalmgrtest"
+)
+
+func TestDownloadAttachment(t *testing.T) {
+	srv := signalmgrtest.NewServer(testNumber)
+	defer srv.Close()
+	ctx := context.Background()
+	srv.AddAttachment("photo.jpg", []byte("jpeg data"), "image/jpeg")
+
+	var buf bytes.Buffer
+	info, err := srv.Client().DownloadAttachment(ctx, "photo.jpg", &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != "jpeg data" || info.ContentType != "image/jpeg" || info.Size != int64(buf.Len()) || info.ID != "photo.jpg" {
+		t.Errorf("got %q with %+v", buf.String(), info)
+	}
+	_, err = srv.Client().DownloadAttachment(ctx, "missing.jpg", &buf)
+	if !errors.Is(err, signalmgr.ErrNotFound) {
+		t.Errorf("missing attachment: got %v, want ErrNotFound", err)
+	}
+}
+
+func TestEncodeAttachment(t *testing.T) {
+	png := []byte("\x89PNG\r\n\x1a\n")
+	tests := []struct {
+		name     string
+		data     []byte
+		filename string
+		mimeType string
+		want     string
+	}{
+		{"given type", []byte("hi"), "notes.txt", "text/markdown", "data:text/markdown;filename=notes.txt;base64,aGk="},
+		{"type from extension", []byte("hi"), "dir/notes.txt", "", "data:text/plain;filename=notes.txt;base64,aGk="},
+		{"type from data", png, "", "", "data:image/png;base64,iVBORw0KGgo="},
+		{"parameters dropped", []byte("hi"), "", "text/plain; charset=utf-8", "data:text/plain;base64,aGk="},
+		{"separators in filename", []byte("hi"), "a;b,c.txt", "", "data:text/plain;filename=a_b_c.txt;base64,aGk="},
+	}
+	for _, tt := range tests {
+		got, err := signalmgr.EncodeAttachment(tt.data, tt.filename, tt.mimeType)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestEncodeAttachmentLimit(t *testing.T) {
+	defer func(size int64) { signalmgr.MaxAttachmentSize = size }(signalmgr.MaxAttachmentSize)
+	signalmgr.MaxAttachmentSize = 4
+	path := filepath.Join(t.TempDir(), "big.bin")
+	if err := os.WriteFile(path, []byte("12345"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	encode := map[string]func(data []byte) (string, error){
+		"bytes": func(data []byte) (string, error) {
+			return signalmgr.EncodeAttachment(data, "a.bin", "")
+		},
+		"reader": func(data []byte) (string, error) {
+			return signalmgr.EncodeAttachmentReader(bytes.NewReader(data), "a.bin", "")
+		},
+	}
+	for name, encode := range encode {
+		if _, err := encode([]byte("1234")); err != nil {
+			t.Errorf("%s: at the limit: %v", name, err)
+		}
+		if _, err := encode([]byte("12345")); !errors.Is(err, signalmgr.ErrAttachmentTooLarge) {
+			t.Errorf("%s: over the limit: got %v, want ErrAttachmentTooLarge", name, err)
+		}
+	}
+	if _, err := signalmgr.EncodeAttachmentFile(path); !errors.Is(err, signalmgr.ErrAttachmentTooLarge) || !strings.Contains(err.Error(), "big.bin is 5 bytes") {
+		t.Errorf("file over the limit: got %v", err)
+	}
+	_, err := signalmgr.NewMessage(testNumber, otherNumber).AttachFile(path).Build()
+	if !errors.Is(err, signalmgr.ErrAttachmentTooLarge) {
+		t.Errorf("builder: got %v, want ErrAttachmentTooLarge", err)
+	}
+}
@@ -0,0 +1,202 @@
+package signalmgr
+
+import (
+	"cmp"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// An Authenticator adds credentials to requests, for a REST API behind a reverse proxy that requires them.
+//
+// It is called before every attempt of a request and before opening a websocket, so it may set headers that change per request.
+type Authenticator interface {
+	Authenticate(ctx context.Context, req *Request) error
+}
+
+// An AuthenticatorFunc is a function used as an Authenticator.
+type AuthenticatorFunc func(ctx context.Context, req *Request) error
+
+func (f AuthenticatorFunc) Authenticate(ctx context.Context, req *Request) error {
+	return f(ctx, req)
+}
+
+// An Authenticator whose credentials can be refreshed after the REST API rejected them.
+type expirer interface {
+	Expire()
+}
+
+// Returns an Authenticator setting headers on every request.
+func HeaderAuth(headers map[string]string) Authenticator {
+	return AuthenticatorFunc(func(ctx context.Context, req *Request) error {
+		for key, val := range headers {
+			req.Header.Set(key, val)
+		}
+		return nil
+	})
+}
+
+// Returns an Authenticator using HTTP basic authentication.
+func BasicAuth(username, password string) Authenticator {
+	credentials := base64.StdEncoding.EncodeToString([]byte(username + ":" + password))
+	return AuthenticatorFunc(func(ctx context.Context, req *Request) error {
+		req.Header.Set("Authorization", "Basic "+credentials)
+		return nil
+	})
+}
+
+// A BearerAuth sends a bearer token in the Authorization header, refreshing it when it expires or is rejected.
+//
+// Its methods are safe for concurrent use.
+type BearerAuth struct {
+	// Returns a new token and when it expires. A zero expiry means the token is used until the REST API rejects it.
+	Refresh func(ctx context.Context) (token string, expiry time.Time, err error)
+	// How long before its expiry a token is refreshed. Defaults to 30 seconds.
+	Leeway time.Duration
+
+	mu     sync.Mutex
+	token  string
+	expiry time.Time
+}
+
+// Creates a BearerAuth that always sends token.
+func StaticBearerAuth(token string) *BearerAuth {
+	return &BearerAuth{token: token}
+}
+
+// Creates a BearerAuth getting its tokens from refresh.
+func NewBearerAuth(refresh func(ctx context.Context) (token string, expiry time.Time, err error)) *BearerAuth {
+	return &BearerAuth{Refresh: refresh}
+}
+
+func (b *BearerAuth) Authenticate(ctx context.Context, req *Request) error {
+	token, err := b.Token(ctx)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	return nil
+}
+
+// Returns the current token, refreshing it first if it expired.
+func (b *BearerAuth) Token(ctx context.Context) (string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	leeway := b.Leeway
+	if leeway <= 0 {
+		leeway = 30 * time.Second
+	}
+	if b.token != "" && (b.expiry.IsZero() || time.Until(b.expiry) > leeway) {
+		return b.token, nil
+	}
+	if b.Refresh == nil {
+		if b.token == "" {
+			return "", errors.New("no bearer token")
+		}
+		return b.token, nil
+	}
+	token, expiry, err := b.Refresh(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to refresh bearer token: %w", err)
+	}
+	b.token, b.expiry = token, expiry
+	return token, nil
+}
+
+// Makes the next request refresh the token. Called when the REST API rejects the token.
+func (b *BearerAuth) Expire() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.Refresh != nil {
+		b.token = ""
+	}
+}
+
+// An HMACAuth signs every request with a shared key, for proxies that verify request signatures.
+//
+// The signature is the base64 HMAC-SHA256 of the method, the request URI, the Unix timestamp in seconds and the hex SHA-256 of the body, joined with newlines:
+//
+//	POST
+//	/v2/send
+//	1700000000
+//	<hex sha256 of body>
+type HMACAuth struct {
+	Key []byte
+	// Identifies the key to the proxy, sent as keyId in the signature header if set.
+	KeyID string
+	// Header the signature is sent in. Defaults to "X-Signature".
+	SignatureHeader string
+	// Header the timestamp is sent in. Defaults to "X-Signature-Timestamp".
+	TimestampHeader string
+	// Returns the current time. Defaults to time.Now.
+	Now func() time.Time
+}
+
+func (h *HMACAuth) Authenticate(ctx context.Context, req *Request) error {
+	u, err := url.Parse(req.URL)
+	if err != nil {
+		return err
+	}
+	now := time.Now
+	if h.Now != nil {
+		now = h.Now
+	}
+	timestamp := strconv.FormatInt(now().Unix(), 10)
+	bodyHash := sha256.Sum256(req.Body)
+
+	mac := hmac.New(sha256.New, h.Key)
+	mac.Write([]byte(strings.Join([]string{req.Method, u.RequestURI(), timestamp, hex.EncodeToString(bodyHash[:])}, "\n")))
+	signature := base64.StdEncoding.EncodeToString(mac.Sum(nil))
+	if h.KeyID != "" {
+		signature = fmt.Sprintf("keyId=%s,signature=%s", h.KeyID, signature)
+	}
+	req.Header.Set(cmp.Or(h.SignatureHeader, "X-Signature"), signature)
+	req.Header.Set(cmp.Or(h.TimestampHeader, "X-Signature-Timestamp"), timestamp)
+	return nil
+}
+
+// Adds the client's credentials to req.
+func (c *Client) authenticate(ctx context.Context, req *Request) error {
+	if c.Auth == nil {
+		return nil
+	}
+	if err := c.Auth.Authenticate(ctx, req); err != nil {
+		return fmt.Errorf("failed to authenticate %s %s: %w", req.Method, req.Path, err)
+	}
+	return nil
+}
+
+// Calls send with an authenticated request from newRequest, and once more with refreshed credentials if the REST API rejected them.
+func (c *Client) authenticated(ctx context.Context, newRequest func() *Request, send func(req *Request) error) (err error) {
+	for retried := false; ; retried = true {
+		req := newRequest()
+		if err = c.authenticate(ctx, req); err != nil {
+			return
+		}
+		err = send(req)
+		if retried || !c.reauthenticate(err) {
+			return
+		}
+	}
+}
+
+// Reports whether err is the REST API rejecting the client's credentials and the client's Authenticator can refresh them, expiring them if so.
+func (c *Client) reauthenticate(err error) bool {
+	e, ok := c.Auth.(expirer)
+	var apiErr *APIError
+	if !ok || !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusUnauthorized {
+		return false
+	}
+	e.Expire()
+	return true
+}
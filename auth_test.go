@@ -0,0 +1,106 @@
+package signalmgr_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DonovanDiamond/signalmgr"
+	"github.com/DonovanDiamond/signalmgr/signalmgrtest"
+)
+
+const (
+	testNumber  = "+14155550123"
+	otherNumber = "+14155550199"
+)
+
+// Starts a Server behind a proxy that only accepts the bearer token "valid", and returns a client for the proxy whose token is rejected until refreshed.
+func newBearerServer(t *testing.T) (*signalmgrtest.Server, *signalmgr.Client, *int) {
+	t.Helper()
+	srv := signalmgrtest.NewServer(testNumber)
+	t.Cleanup(srv.Close)
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer valid" {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"error":"unauthorized"}`))
+			return
+		}
+		srv.Config.Handler.ServeHTTP(w, r)
+	}))
+	t.Cleanup(proxy.Close)
+
+	refreshes := 0
+	client := signalmgr.NewClient(proxy.URL)
+	client.Auth = signalmgr.NewBearerAuth(func(ctx context.Context) (string, time.Time, error) {
+		refreshes++
+		if refreshes == 1 {
+			return "stale", time.Time{}, nil
+		}
+		return "valid", time.Now().Add(time.Hour), nil
+	})
+	return srv, client, &refreshes
+}
+
+func TestBearerAuthRefreshesRejectedToken(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	t.Run("request", func(t *testing.T) {
+		_, client, refreshes := newBearerServer(t)
+		if _, err := client.Account(testNumber).GetGroupsCtx(ctx); err != nil {
+			t.Fatal(err)
+		}
+		if *refreshes != 2 {
+			t.Errorf("got %d refreshes, want 2", *refreshes)
+		}
+	})
+
+	t.Run("stream", func(t *testing.T) {
+		srv, client, refreshes := newBearerServer(t)
+		srv.AddAttachment("photo", []byte("data"), "image/png")
+		var buf bytes.Buffer
+		if _, err := client.DownloadAttachment(ctx, "photo", &buf); err != nil {
+			t.Fatal(err)
+		}
+		if buf.String() != "data" || *refreshes != 2 {
+			t.Errorf("got %q after %d refreshes, want %q after 2", buf.String(), *refreshes, "data")
+		}
+	})
+
+	t.Run("websocket", func(t *testing.T) {
+		srv, client, refreshes := newBearerServer(t)
+		messages := make(chan signalmgr.MessageResponse, 1)
+		errs := make(chan error, 1)
+		go func() {
+			errs <- client.Account(testNumber).GetMessagesSocketCtx(ctx, messages)
+		}()
+		for srv.Connections(testNumber) == 0 {
+			select {
+			case err := <-errs:
+				t.Fatal(err)
+			case <-time.After(10 * time.Millisecond):
+			}
+		}
+		srv.Deliver(testNumber, signalmgrtest.TextEnvelope(otherNumber, "hi"))
+		if m := <-messages; m.Text() != "hi" || *refreshes != 2 {
+			t.Errorf("got %q after %d refreshes, want %q after 2", m.Text(), *refreshes, "hi")
+		}
+	})
+}
+
+func TestWebsocketHandshakeError(t *testing.T) {
+	srv := signalmgrtest.NewServer(testNumber)
+	defer srv.Close()
+	srv.FailNext(http.MethodGet, "/v1/receive/"+testNumber, http.StatusUnauthorized, "unauthorized")
+
+	err := srv.Account(testNumber).GetMessagesSocketCtx(context.Background(), make(chan signalmgr.MessageResponse))
+	var apiErr *signalmgr.APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("got %v, want an APIError with status 401", err)
+	}
+}
@@ -0,0 +1,287 @@
+package signalmgr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/DonovanDiamond/signalmgr/signaltypes"
+)
+
+// A Command that a Bot responds to, e.g. `/deploy api v1.2`.
+type Command struct {
+	// Name of the command without the prefix, e.g. "deploy". Matched case insensitively.
+	Name string
+	// Arguments shown in the help text, e.g. "<service> [version]".
+	Usage string
+	// Short description shown in the help text.
+	Help string
+	// Minimum number of arguments.
+	MinArgs int
+	// Maximum number of arguments. Zero or less means no maximum.
+	MaxArgs int
+	// Decides if the sender may run the command. If nil, everyone may.
+	Permission Permission
+	// Runs the command.
+	Handler func(ctx context.Context, cmd *CommandContext) error
+}
+
+// A CommandContext describes a single invocation of a Command.
+type CommandContext struct {
+	// Bot that received the command.
+	Bot *Bot
+	// Message the command was sent in.
+	Message MessageResponse
+	// Command being run.
+	Command *Command
+	// Arguments after the command name, split on spaces. Quotes group words into one argument.
+	Args []string
+	// Arguments after the command name, as sent.
+	RawArgs string
+}
+
+// Sends text to the conversation the command was sent in, quoting the command.
+func (c *CommandContext) Reply(ctx context.Context, text string) error {
+	return c.Bot.reply(ctx, c.Message, text)
+}
+
+// Decides if the sender of cmd may run it. It is called before the arguments are parsed, so cmd.Args is nil.
+type Permission func(ctx context.Context, cmd *CommandContext) (bool, error)
+
+// Allows senders whose number or UUID is in ids.
+func AllowSenders(ids ...string) Permission {
+	return func(ctx context.Context, cmd *CommandContext) (bool, error) {
+		e := cmd.Message.Envelope
+		return (e.SourceNumber != "" && slices.Contains(ids, e.SourceNumber)) ||
+			(e.SourceUuid != "" && slices.Contains(ids, e.SourceUuid)), nil
+	}
+}
+
+// Allows senders that are an admin of the group the command was sent in. Commands sent outside of a group are denied.
+func AllowGroupAdmins() Permission {
+	return func(ctx context.Context, cmd *CommandContext) (bool, error) {
+		groupID := cmd.Message.GroupID()
+		if groupID == "" {
+			return false, nil
+		}
+		group, err := cmd.Bot.Account.GetGroupCtx(ctx, groupRecipient(groupID))
+		if err != nil {
+			return false, fmt.Errorf("failed to get group admins: %w", err)
+		}
+		e := cmd.Message.Envelope
+		return (e.SourceNumber != "" && slices.Contains(group.Admins, e.SourceNumber)) ||
+			(e.SourceUuid != "" && slices.Contains(group.Admins, e.SourceUuid)), nil
+	}
+}
+
+// Allows senders allowed by any of permissions.
+func AnyOf(permissions ...Permission) Permission {
+	return func(ctx context.Context, cmd *CommandContext) (bool, error) {
+		for _, p := range permissions {
+			if ok, err := p(ctx, cmd); err != nil || ok {
+				return ok, err
+			}
+		}
+		return false, nil
+	}
+}
+
+// A Bot runs commands sent to an account as text messages starting with Prefix.
+//
+// A `help` command listing all commands is built in, unless a command with that name is registered.
+type Bot struct {
+	// Account the bot receives and replies with.
+	Account *Account
+	// Prefix commands start with. Defaults to "/".
+	Prefix string
+
+	mu       sync.RWMutex
+	commands map[string]*Command
+}
+
+// Creates a Bot for account.
+func NewBot(account *Account) *Bot {
+	return &Bot{Account: account}
+}
+
+func (b *Bot) prefix() string {
+	if b.Prefix == "" {
+		return "/"
+	}
+	return b.Prefix
+}
+
+// Registers cmd, replacing any command with the same name.
+func (b *Bot) Register(cmd Command) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.commands == nil {
+		b.commands = map[string]*Command{}
+	}
+	b.commands[strings.ToLower(cmd.Name)] = &cmd
+}
+
+// Returns the help text listing every registered command.
+func (b *Bot) HelpText() string {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	names := make([]string, 0, len(b.commands))
+	for name := range b.commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var sb strings.Builder
+	sb.WriteString("Commands:")
+	for _, name := range names {
+		cmd := b.commands[name]
+		fmt.Fprintf(&sb, "\n%s%s", b.prefix(), cmd.Name)
+		if cmd.Usage != "" {
+			fmt.Fprintf(&sb, " %s", cmd.Usage)
+		}
+		if cmd.Help != "" {
+			fmt.Fprintf(&sb, " - %s", cmd.Help)
+		}
+	}
+	return sb.String()
+}
+
+// Registers the bot's command handling on the router for the bot's account.
+func (b *Bot) Attach(r *Router) {
+	r.Account(b.Account.Number).OnText(func(ctx context.Context, m MessageResponse, msg signaltypes.DataMessage) error {
+		return b.HandleMessage(ctx, m)
+	})
+}
+
+// Receives messages for the bot's account and runs the commands in them.
+//
+// Will only return once ctx is done or receiving fails, see Receive.
+func (b *Bot) Run(ctx context.Context, opts *ReceiveOptions) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	router := NewRouter()
+	b.Attach(router)
+
+	messages := make(chan MessageResponse)
+	errs := make(chan error, 1)
+	go func() {
+		errs <- b.Account.Receive(ctx, messages, opts)
+		cancel()
+	}()
+	router.Run(ctx, messages)
+	return <-errs
+}
+
+// Runs the command in m, if its text starts with the bot's prefix.
+//
+// Unknown commands, invalid arguments and denied permissions are answered with a reply. Errors returned by the command's handler are returned.
+func (b *Bot) HandleMessage(ctx context.Context, m MessageResponse) error {
+	text := strings.TrimSpace(m.Envelope.DataMessage.Message)
+	if !strings.HasPrefix(text, b.prefix()) {
+		return nil
+	}
+	text = strings.TrimPrefix(text, b.prefix())
+	name, rawArgs := text, ""
+	if i := strings.IndexFunc(text, unicode.IsSpace); i >= 0 {
+		name, rawArgs = text[:i], strings.TrimSpace(text[i:])
+	}
+	if name == "" {
+		return nil
+	}
+
+	b.mu.RLock()
+	cmd, ok := b.commands[strings.ToLower(name)]
+	b.mu.RUnlock()
+	if !ok {
+		if strings.EqualFold(name, "help") {
+			return b.reply(ctx, m, b.HelpText())
+		}
+		return b.reply(ctx, m, fmt.Sprintf("Unknown command %s%s, send %shelp for a list of commands.", b.prefix(), name, b.prefix()))
+	}
+
+	cc := &CommandContext{
+		Bot:     b,
+		Message: m,
+		Command: cmd,
+		RawArgs: rawArgs,
+	}
+
+	// Permission is checked before the arguments, so senders who cannot run a command are told so instead of being shown its usage.
+	if cmd.Permission != nil {
+		allowed, err := cmd.Permission(ctx, cc)
+		if err != nil {
+			return fmt.Errorf("failed to check permission for %s: %w", cmd.Name, err)
+		}
+		if !allowed {
+			return b.reply(ctx, m, fmt.Sprintf("You are not allowed to use %s%s.", b.prefix(), cmd.Name))
+		}
+	}
+	args, err := ParseArgs(rawArgs)
+	if err != nil {
+		return b.reply(ctx, m, fmt.Sprintf("Invalid arguments: %s.", err))
+	}
+	cc.Args = args
+	if len(args) < cmd.MinArgs || (cmd.MaxArgs > 0 && len(args) > cmd.MaxArgs) {
+		return b.reply(ctx, m, fmt.Sprintf("Usage: %s%s %s", b.prefix(), cmd.Name, cmd.Usage))
+	}
+	if cmd.Handler == nil {
+		return nil
+	}
+	return cmd.Handler(ctx, cc)
+}
+
+// Sends text to the conversation of m, quoting m.
+func (b *Bot) reply(ctx context.Context, m MessageResponse, text string) error {
+	m.Account, m.client = b.Account.Number, b.Account.client()
+	_, err := m.ReplyQuoted(ctx, text)
+	return err
+}
+
+// Splits s into arguments on whitespace. Single or double quotes group words into one argument and a backslash escapes the next character.
+func ParseArgs(s string) (args []string, err error) {
+	var current strings.Builder
+	var quote rune
+	inArg, escaped := false, false
+	for _, r := range s {
+		switch {
+		case escaped:
+			current.WriteRune(r)
+			escaped = false
+		case r == '\\':
+			escaped, inArg = true, true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				current.WriteRune(r)
+			}
+		case r == '"' || r == '\'':
+			quote, inArg = r, true
+		case unicode.IsSpace(r):
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+		default:
+			current.WriteRune(r)
+			inArg = true
+		}
+	}
+	if quote != 0 {
+		return nil, errors.New("unterminated quote")
+	}
+	if escaped {
+		return nil, errors.New("trailing backslash")
+	}
+	if inArg {
+		args = append(args, current.String())
+	}
+	return
+}
@@ -0,0 +1,117 @@
+package signalmgr_test
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DonovanDiamond/signalmgr"
+	"github.com/DonovanDiamond/signalmgr/signalmgrtest"
+)
+
+func TestParseArgs(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    []string
+		wantErr bool
+	}{
+		{in: "", want: nil},
+		{in: "   ", want: nil},
+		{in: "api v1.2", want: []string{"api", "v1.2"}},
+		{in: "  api \t v1.2  ", want: []string{"api", "v1.2"}},
+		{in: `"hello world" x`, want: []string{"hello world", "x"}},
+		{in: `'single "quoted"'`, want: []string{`single "quoted"`}},
+		{in: `a\ b`, want: []string{"a b"}},
+		{in: `\"`, want: []string{`"`}},
+		{in: `""`, want: []string{""}},
+		{in: `pre"fix suf"fix`, want: []string{"prefix suffix"}},
+		{in: `"unterminated`, wantErr: true},
+		{in: `trailing\`, wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := signalmgr.ParseArgs(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseArgs(%q) error = %v, want error %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("ParseArgs(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+// Runs bot against srv until the test ends.
+func runBot(t *testing.T, bot *signalmgr.Bot) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		bot.Run(ctx, nil)
+		close(done)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+}
+
+func TestBotCommands(t *testing.T) {
+	srv := signalmgrtest.NewServer(testNumber)
+	defer srv.Close()
+
+	var gotArgs []string
+	bot := signalmgr.NewBot(srv.Account(testNumber))
+	bot.Register(signalmgr.Command{
+		Name:    "deploy",
+		MinArgs: 1,
+		Handler: func(ctx context.Context, cmd *signalmgr.CommandContext) error {
+			gotArgs = cmd.Args
+			return cmd.Reply(ctx, "deploying "+cmd.Args[0])
+		},
+	})
+	bot.Register(signalmgr.Command{
+		Name:       "secret",
+		Permission: signalmgr.AllowSenders("+14155550100"),
+		Handler: func(ctx context.Context, cmd *signalmgr.CommandContext) error {
+			t.Error("secret ran for an unauthorized sender")
+			return nil
+		},
+	})
+	runBot(t, bot)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	tests := []struct {
+		text string
+		want string
+	}{
+		{text: `/deploy "api server" v2`, want: "deploying api server"},
+		{text: "/deploy", want: "Usage: /deploy"},
+		{text: "/nope", want: "Unknown command /nope"},
+		// Permission is checked before the arguments, so the unterminated quote is not reported.
+		{text: `/secret "unterminated`, want: "You are not allowed to use /secret."},
+		{text: `/deploy "unterminated`, want: "Invalid arguments: unterminated quote."},
+	}
+	for i, tt := range tests {
+		for srv.Connections(testNumber) == 0 {
+			time.Sleep(5 * time.Millisecond)
+		}
+		srv.Deliver(testNumber, signalmgrtest.TextEnvelope(otherNumber, tt.text))
+		sent, err := srv.WaitSent(ctx, i+1)
+		if err != nil {
+			t.Fatalf("%q: no reply: %v", tt.text, err)
+		}
+		reply := sent[i]
+		if !strings.HasPrefix(reply.Message, tt.want) {
+			t.Errorf("%q: got reply %q, want prefix %q", tt.text, reply.Message, tt.want)
+		}
+		if !slices.Equal(reply.Recipients, []string{otherNumber}) || reply.QuoteTimestamp == nil {
+			t.Errorf("%q: reply to %v quoting %v, want a quoted reply to %s", tt.text, reply.Recipients, reply.QuoteTimestamp, otherNumber)
+		}
+	}
+	if !slices.Equal(gotArgs, []string{"api server", "v2"}) {
+		t.Errorf("got args %q", gotArgs)
+	}
+}
@@ -0,0 +1,236 @@
+package signalmgr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf16"
+)
+
+// Markers used by the REST API to style text when the text mode is `styled`.
+var styleMarkers = []string{"**", "*", "~", "||", "`"}
+
+// A MessageBuilder builds a SendMessageV2, taking care of mention offsets, styling, quotes and edits.
+//
+// Methods add to the message in the order they are called and return the builder so calls can be chained. Errors are reported by Build and Send.
+type MessageBuilder struct {
+	account *Account
+	msg     SendMessageV2
+	text    strings.Builder
+	// Length of the text without style markers, in UTF-16 code units.
+	length int
+	styled bool
+	// Marker of the styled text the message ends with, if any.
+	lastMarker string
+	plain      []string
+	errs       []error
+}
+
+// Starts a message from number to recipients, sent with DefaultClient.
+func NewMessage(number string, recipients ...string) *MessageBuilder {
+	return (&Account{Number: number}).NewMessage(recipients...)
+}
+
+// Starts a message from this account to recipients.
+func (a *Account) NewMessage(recipients ...string) *MessageBuilder {
+	b := &MessageBuilder{account: a}
+	b.msg.Number = a.Number
+	b.msg.Recipients = recipients
+	return b
+}
+
+// Adds recipients. Groups are addressed by their `group.` id.
+func (b *MessageBuilder) To(recipients ...string) *MessageBuilder {
+	b.msg.Recipients = append(b.msg.Recipients, recipients...)
+	return b
+}
+
+func utf16Len(s string) int {
+	return len(utf16.Encode([]rune(s)))
+}
+
+// Adds plain text.
+func (b *MessageBuilder) Text(text string) *MessageBuilder {
+	if text != "" {
+		b.lastMarker = ""
+	}
+	b.text.WriteString(text)
+	b.length += utf16Len(text)
+	b.plain = append(b.plain, text)
+	return b
+}
+
+// Adds formatted text.
+func (b *MessageBuilder) Textf(format string, args ...any) *MessageBuilder {
+	return b.Text(fmt.Sprintf(format, args...))
+}
+
+func (b *MessageBuilder) style(marker, text string) *MessageBuilder {
+	if text == "" {
+		return b
+	}
+	b.styled = true
+	switch b.lastMarker {
+	case "":
+		b.text.WriteString(marker + text + marker)
+	case marker:
+		// Adjacent text in the same style is merged into one span, as "**a****b**" would not parse.
+		styled := strings.TrimSuffix(b.text.String(), marker)
+		b.text.Reset()
+		b.text.WriteString(styled + text + marker)
+	default:
+		b.errs = append(b.errs, fmt.Errorf("%q text directly after %q text is ambiguous, separate them with plain text", marker, b.lastMarker))
+		b.text.WriteString(marker + text + marker)
+	}
+	b.lastMarker = marker
+	b.length += utf16Len(text)
+	b.plain = append(b.plain, text)
+	return b
+}
+
+// Adds bold text.
+func (b *MessageBuilder) Bold(text string) *MessageBuilder {
+	return b.style("**", text)
+}
+
+// Adds italic text.
+func (b *MessageBuilder) Italic(text string) *MessageBuilder {
+	return b.style("*", text)
+}
+
+// Adds strikethrough text.
+func (b *MessageBuilder) Strikethrough(text string) *MessageBuilder {
+	return b.style("~", text)
+}
+
+// Adds text hidden as a spoiler.
+func (b *MessageBuilder) Spoiler(text string) *MessageBuilder {
+	return b.style("||", text)
+}
+
+// Adds monospace text.
+func (b *MessageBuilder) Monospace(text string) *MessageBuilder {
+	return b.style("`", text)
+}
+
+// Adds an @mention of author, the number or UUID of the mentioned user.
+//
+// display is the placeholder text, e.g. "@Bob", which Signal replaces with the user's name. Defaults to "@".
+func (b *MessageBuilder) Mention(author, display string) *MessageBuilder {
+	if author == "" {
+		b.errs = append(b.errs, errors.New("mention without author"))
+		return b
+	}
+	if display == "" {
+		display = "@"
+	}
+	length := utf16Len(display)
+	b.msg.Mentions = append(b.msg.Mentions, SendMessageV2_MessageMention{
+		Start:  int64(b.length),
+		Length: int64(length),
+		Author: author,
+	})
+	b.text.WriteString(display)
+	b.lastMarker = ""
+	b.length += length
+	b.plain = append(b.plain, display)
+	return b
+}
+
+// Adds attachments, formatted as `data:<MIME-TYPE>;filename=<FILENAME>;base64,<BASE64 ENCODED DATA>`.
+func (b *MessageBuilder) Attach(base64Attachments ...string) *MessageBuilder {
+	b.msg.Base64Attachments = append(b.msg.Base64Attachments, base64Attachments...)
+	return b
+}
+
+// Quotes the message sent by author at timestamp, with text as the quoted text.
+func (b *MessageBuilder) Quote(timestamp int64, author, text string) *MessageBuilder {
+	b.msg.QuoteTimestamp = &timestamp
+	b.msg.QuoteAuthor = &author
+	b.msg.QuoteMessage = &text
+	return b
+}
+
+// Quotes the received message m.
+func (b *MessageBuilder) QuoteMessage(m MessageResponse) *MessageBuilder {
+	return b.Quote(m.Timestamp(), m.Sender(), m.Text())
+}
+
+// Sends the sticker with stickerID from the installed pack packID. Stickers cannot be combined with text or attachments.
+func (b *MessageBuilder) Sticker(packID string, stickerID int) *MessageBuilder {
+	b.msg.Sticker = fmt.Sprintf("%s:%d", packID, stickerID)
+	return b
+}
+
+// Makes the message replace the text of our message sent at timestamp.
+func (b *MessageBuilder) Edit(timestamp int64) *MessageBuilder {
+	b.msg.EditTimestamp = &timestamp
+	return b
+}
+
+// Sets whether the message also notifies our own linked devices.
+func (b *MessageBuilder) NotifySelf(notify bool) *MessageBuilder {
+	b.msg.NotifySelf = &notify
+	return b
+}
+
+// Validates and returns the message.
+func (b *MessageBuilder) Build() (msg SendMessageV2, err error) {
+	errs := append([]error{}, b.errs...)
+	msg = b.msg
+	msg.Message = b.text.String()
+	if b.styled {
+		mode := "styled"
+		msg.TextMode = &mode
+		for _, text := range b.plain {
+			for _, marker := range styleMarkers {
+				if strings.Contains(text, marker) {
+					errs = append(errs, fmt.Errorf("text %q contains the style marker %q, which would be styled", text, marker))
+					break
+				}
+			}
+		}
+	}
+
+	if msg.Number == "" {
+		errs = append(errs, errors.New("no sender number"))
+	}
+	if len(msg.Recipients) == 0 {
+		errs = append(errs, errors.New("no recipients"))
+	}
+	if msg.Message == "" && len(msg.Base64Attachments) == 0 && msg.Sticker == "" {
+		errs = append(errs, errors.New("no text, attachments or sticker"))
+	}
+	if msg.Sticker != "" && (msg.Message != "" || len(msg.Base64Attachments) > 0) {
+		errs = append(errs, errors.New("stickers cannot be sent with text or attachments"))
+	}
+	if msg.QuoteTimestamp != nil && (*msg.QuoteTimestamp <= 0 || *msg.QuoteAuthor == "") {
+		errs = append(errs, errors.New("quote needs a timestamp and author"))
+	}
+	if msg.EditTimestamp != nil {
+		if *msg.EditTimestamp <= 0 {
+			errs = append(errs, errors.New("edit needs the timestamp of the message being edited"))
+		} else if sent := time.UnixMilli(*msg.EditTimestamp); time.Since(sent) > EditWindow {
+			errs = append(errs, fmt.Errorf("message sent at %s: %w", sent.Format(time.RFC3339), ErrEditWindowExpired))
+		}
+		if msg.Sticker != "" {
+			errs = append(errs, errors.New("stickers cannot be edited"))
+		}
+	}
+
+	if err = errors.Join(errs...); err != nil {
+		err = fmt.Errorf("invalid message: %w", err)
+	}
+	return
+}
+
+// Validates and sends the message.
+func (b *MessageBuilder) Send(ctx context.Context) (result SendResult, err error) {
+	msg, err := b.Build()
+	if err != nil {
+		return
+	}
+	return b.account.client().PostSendCtx(ctx, msg)
+}
@@ -0,0 +1,123 @@
+package signalmgr_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DonovanDiamond/signalmgr"
+)
+
+func TestBuilderMentionOffsets(t *testing.T) {
+	msg, err := signalmgr.NewMessage(testNumber, otherNumber).
+		Text("👋 ").
+		Mention(otherNumber, "@Bob").
+		Text(" and é ").
+		Mention("uuid-1", "").
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.Message != "👋 @Bob and é @" {
+		t.Errorf("got text %q", msg.Message)
+	}
+	// Offsets are in UTF-16 code units: the emoji is a surrogate pair.
+	want := []signalmgr.SendMessageV2_MessageMention{
+		{Start: 3, Length: 4, Author: otherNumber},
+		{Start: 14, Length: 1, Author: "uuid-1"},
+	}
+	if len(msg.Mentions) != len(want) {
+		t.Fatalf("got mentions %+v, want %+v", msg.Mentions, want)
+	}
+	for i := range want {
+		if msg.Mentions[i] != want[i] {
+			t.Errorf("mention %d = %+v, want %+v", i, msg.Mentions[i], want[i])
+		}
+	}
+}
+
+func TestBuilderStyles(t *testing.T) {
+	tests := []struct {
+		name    string
+		build   func(b *signalmgr.MessageBuilder) *signalmgr.MessageBuilder
+		want    string
+		wantErr string
+	}{
+		{
+			name:  "separated",
+			build: func(b *signalmgr.MessageBuilder) *signalmgr.MessageBuilder { return b.Bold("a").Text(" ").Italic("b") },
+			want:  "**a** *b*",
+		},
+		{
+			name:  "adjacent same style is merged",
+			build: func(b *signalmgr.MessageBuilder) *signalmgr.MessageBuilder { return b.Bold("a").Bold("b").Text("!") },
+			want:  "**ab**!",
+		},
+		{
+			name: "merged after plain text",
+			build: func(b *signalmgr.MessageBuilder) *signalmgr.MessageBuilder {
+				return b.Text("x ").Spoiler("a").Spoiler("b")
+			},
+			want: "x ||ab||",
+		},
+		{
+			name:    "adjacent different styles",
+			build:   func(b *signalmgr.MessageBuilder) *signalmgr.MessageBuilder { return b.Italic("a").Bold("b") },
+			wantErr: "ambiguous",
+		},
+		{
+			name: "empty text does not separate",
+			build: func(b *signalmgr.MessageBuilder) *signalmgr.MessageBuilder {
+				return b.Monospace("a").Text("").Monospace("b")
+			},
+			want: "`ab`",
+		},
+		{
+			name:    "marker in styled message",
+			build:   func(b *signalmgr.MessageBuilder) *signalmgr.MessageBuilder { return b.Bold("a").Text(" 2*3") },
+			wantErr: "style marker",
+		},
+	}
+	for _, tt := range tests {
+		msg, err := tt.build(signalmgr.NewMessage(testNumber, otherNumber)).Build()
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("%s: got error %v, want %q", tt.name, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if msg.Message != tt.want || msg.TextMode == nil || *msg.TextMode != "styled" {
+			t.Errorf("%s: got %q in mode %v, want %q styled", tt.name, msg.Message, msg.TextMode, tt.want)
+		}
+	}
+}
+
+func TestBuilderValidation(t *testing.T) {
+	tests := []struct {
+		name string
+		b    *signalmgr.MessageBuilder
+		want string
+	}{
+		{"no recipients", signalmgr.NewMessage(testNumber).Text("hi"), "no recipients"},
+		{"empty", signalmgr.NewMessage(testNumber, otherNumber), "no text"},
+		{"sticker with text", signalmgr.NewMessage(testNumber, otherNumber).Text("hi").Sticker("pack", 1), "stickers cannot"},
+		{"mention without author", signalmgr.NewMessage(testNumber, otherNumber).Mention("", "@x"), "mention without author"},
+	}
+	for _, tt := range tests {
+		_, err := tt.b.Build()
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: got error %v, want %q", tt.name, err, tt.want)
+		}
+	}
+
+	old := time.Now().Add(-signalmgr.EditWindow - time.Minute).UnixMilli()
+	_, err := signalmgr.NewMessage(testNumber, otherNumber).Text("fixed").Edit(old).Build()
+	if !errors.Is(err, signalmgr.ErrEditWindowExpired) {
+		t.Errorf("old edit: got %v, want ErrEditWindowExpired", err)
+	}
+}
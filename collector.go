@@ -0,0 +1,227 @@
+package signalmgr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Why an AttachmentCollector deletes an attachment.
+type CollectReason string
+
+const (
+	// The attachment is older than MaxAge.
+	CollectMaxAge CollectReason = "max age"
+	// There are more than MaxCount attachments and this is one of the oldest.
+	CollectMaxCount CollectReason = "max count"
+	// No message seen within UnreferencedFor referenced the attachment.
+	CollectUnreferenced CollectReason = "unreferenced"
+)
+
+// An attachment deleted, or in dry-run mode selected for deletion, by an AttachmentCollector.
+type CollectedAttachment struct {
+	ID     string
+	Reason CollectReason
+	// When the attachment was uploaded, or first listed if that is unknown.
+	Added time.Time
+}
+
+// Result of a single AttachmentCollector run.
+type CollectReport struct {
+	// When the run started.
+	Time time.Time
+	// Whether this was a dry run, in which case nothing was deleted.
+	DryRun bool
+	// Number of attachments on the REST API before the run.
+	Listed int
+	// Attachments deleted, or in dry-run mode selected for deletion.
+	Deleted []CollectedAttachment
+	// Error of the run, joining the errors of deletes that failed.
+	Err error
+}
+
+// An AttachmentCollector deletes attachments from the REST API container according to its policy, on a schedule or on demand.
+//
+// The REST API only lists attachment ids, so ages and references are learned from the messages passed to Observe. Attachments never observed are aged from when the collector first listed them.
+type AttachmentCollector struct {
+	// Client whose attachments are collected. If nil, DefaultClient is used.
+	Client *Client
+	// Delete attachments added longer than MaxAge ago. Zero disables this.
+	MaxAge time.Duration
+	// Delete the oldest attachments while there are more than MaxCount. Zero disables this.
+	MaxCount int
+	// Delete attachments not referenced by any message observed within UnreferencedFor. Zero disables this.
+	UnreferencedFor time.Duration
+	// Interval between runs of Run. Defaults to one hour.
+	Interval time.Duration
+	// Only report what would be deleted.
+	DryRun bool
+	// Called with the report of each run of Run.
+	OnCollect func(report CollectReport)
+
+	mu         sync.Mutex
+	added      map[string]time.Time
+	referenced map[string]time.Time
+}
+
+// Creates an AttachmentCollector for client's attachments, with no policy set.
+func NewAttachmentCollector(client *Client) *AttachmentCollector {
+	return &AttachmentCollector{Client: client}
+}
+
+// Records the attachments referenced by m, and when they were uploaded.
+func (c *AttachmentCollector) Observe(m MessageResponse) {
+	attachments := messageAttachments(m)
+	if len(attachments) == 0 {
+		return
+	}
+	now := time.Now()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.init()
+	for _, attachment := range attachments {
+		c.referenced[attachment.Id] = now
+		if attachment.UploadTimestamp > 0 {
+			c.added[attachment.Id] = time.UnixMilli(attachment.UploadTimestamp)
+		} else if _, ok := c.added[attachment.Id]; !ok {
+			c.added[attachment.Id] = now
+		}
+	}
+}
+
+// Returns middleware that observes every message passing through a Router.
+func (c *AttachmentCollector) Middleware() Middleware {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, m MessageResponse) error {
+			c.Observe(m)
+			return next(ctx, m)
+		}
+	}
+}
+
+func (c *AttachmentCollector) init() {
+	if c.added == nil {
+		c.added = map[string]time.Time{}
+		c.referenced = map[string]time.Time{}
+	}
+}
+
+func (c *AttachmentCollector) client() *Client {
+	if c.Client == nil {
+		return DefaultClient
+	}
+	return c.Client
+}
+
+// Lists the attachments on the REST API and deletes those selected by the policy, or only reports them in dry-run mode.
+func (c *AttachmentCollector) Collect(ctx context.Context) (report CollectReport, err error) {
+	report = CollectReport{Time: time.Now(), DryRun: c.DryRun}
+	ids, err := c.client().GetAttachmentsCtx(ctx)
+	if err != nil {
+		report.Err = fmt.Errorf("failed to list attachments: %w", err)
+		return report, report.Err
+	}
+	report.Listed = len(ids)
+	report.Deleted = c.selectAttachments(report.Time, ids)
+
+	if !c.DryRun {
+		var errs []error
+		deleted := report.Deleted[:0]
+		for _, attachment := range report.Deleted {
+			if err := c.client().DeleteAttachmentCtx(ctx, attachment.ID); err != nil {
+				errs = append(errs, fmt.Errorf("failed to delete attachment %s: %w", attachment.ID, err))
+				continue
+			}
+			deleted = append(deleted, attachment)
+			c.forget(attachment.ID)
+		}
+		report.Deleted = deleted
+		report.Err = errors.Join(errs...)
+	}
+	return report, report.Err
+}
+
+// Returns the attachments among ids that the policy deletes at now, and forgets attachments no longer listed.
+func (c *AttachmentCollector) selectAttachments(now time.Time, ids []string) (selected []CollectedAttachment) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.init()
+
+	listed := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		listed[id] = true
+		if _, ok := c.added[id]; !ok {
+			c.added[id] = now
+		}
+	}
+	for id := range c.added {
+		if !listed[id] {
+			delete(c.added, id)
+			delete(c.referenced, id)
+		}
+	}
+
+	var kept []CollectedAttachment
+	for _, id := range ids {
+		attachment := CollectedAttachment{ID: id, Added: c.added[id]}
+		lastReferenced, ok := c.referenced[id]
+		if !ok {
+			lastReferenced = attachment.Added
+		}
+		switch {
+		case c.MaxAge > 0 && now.Sub(attachment.Added) > c.MaxAge:
+			attachment.Reason = CollectMaxAge
+		case c.UnreferencedFor > 0 && now.Sub(lastReferenced) > c.UnreferencedFor:
+			attachment.Reason = CollectUnreferenced
+		default:
+			kept = append(kept, attachment)
+			continue
+		}
+		selected = append(selected, attachment)
+	}
+
+	if c.MaxCount > 0 && len(kept) > c.MaxCount {
+		sort.SliceStable(kept, func(i, j int) bool { return kept[i].Added.Before(kept[j].Added) })
+		for _, attachment := range kept[:len(kept)-c.MaxCount] {
+			attachment.Reason = CollectMaxCount
+			selected = append(selected, attachment)
+		}
+	}
+	return
+}
+
+func (c *AttachmentCollector) forget(id string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.added, id)
+	delete(c.referenced, id)
+}
+
+// Runs Collect every Interval, passing each report to OnCollect, until ctx is done.
+//
+// Failed runs are reported and retried at the next interval. Returns ctx.Err().
+func (c *AttachmentCollector) Run(ctx context.Context) error {
+	interval := c.Interval
+	if interval <= 0 {
+		interval = time.Hour
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		report, _ := c.Collect(ctx)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if c.OnCollect != nil {
+			c.OnCollect(report)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package signalmgr_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/DonovanDiamond/signalmgr"
+	"github.com/DonovanDiamond/signalmgr/signalmgrtest"
+	"github.com/DonovanDiamond/signalmgr/signaltypes"
+)
+
+// Returns a message referencing attachments uploaded the given time ago, by id.
+func withAttachments(uploaded map[string]time.Duration) signalmgr.MessageResponse {
+	e := signalmgrtest.TextEnvelope(otherNumber, "files")
+	for id, ago := range uploaded {
+		e.DataMessage.Attachments = append(e.DataMessage.Attachments, signaltypes.Attachment{
+			Id:              id,
+			UploadTimestamp: time.Now().Add(-ago).UnixMilli(),
+		})
+	}
+	return signalmgr.MessageResponse{Envelope: e, Account: testNumber}
+}
+
+func collected(report signalmgr.CollectReport) map[string]signalmgr.CollectReason {
+	reasons := map[string]signalmgr.CollectReason{}
+	for _, attachment := range report.Deleted {
+		reasons[attachment.ID] = attachment.Reason
+	}
+	return reasons
+}
+
+func remaining(srv *signalmgrtest.Server) []string {
+	var ids []string
+	for id := range srv.Attachments() {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	return ids
+}
+
+func TestAttachmentCollector(t *testing.T) {
+	tests := []struct {
+		name     string
+		maxAge   time.Duration
+		maxCount int
+		want     map[string]signalmgr.CollectReason
+	}{
+		{"no policy", 0, 0, map[string]signalmgr.CollectReason{}},
+		{"max age", 150 * time.Minute, 0, map[string]signalmgr.CollectReason{"older": signalmgr.CollectMaxAge}},
+		{"max count keeps the newest", 0, 2, map[string]signalmgr.CollectReason{
+			"old":   signalmgr.CollectMaxCount,
+			"older": signalmgr.CollectMaxCount,
+		}},
+		{"max age before max count", 150 * time.Minute, 2, map[string]signalmgr.CollectReason{
+			"older": signalmgr.CollectMaxAge,
+			"old":   signalmgr.CollectMaxCount,
+		}},
+	}
+	for _, tt := range tests {
+		srv := signalmgrtest.NewServer(testNumber)
+		for _, id := range []string{"older", "old", "new", "unseen"} {
+			srv.AddAttachment(id, []byte(id), "text/plain")
+		}
+		c := &signalmgr.AttachmentCollector{Client: srv.Client(), MaxAge: tt.maxAge, MaxCount: tt.maxCount}
+		c.Observe(withAttachments(map[string]time.Duration{"older": 3 * time.Hour, "old": 2 * time.Hour, "new": time.Minute}))
+
+		report, err := c.Collect(context.Background())
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+		}
+		if report.Listed != 4 {
+			t.Errorf("%s: listed %d, want 4", tt.name, report.Listed)
+		}
+		got := collected(report)
+		if len(got) != len(tt.want) {
+			t.Errorf("%s: deleted %v, want %v", tt.name, got, tt.want)
+		}
+		for id, reason := range tt.want {
+			if got[id] != reason {
+				t.Errorf("%s: deleted %v, want %v", tt.name, got, tt.want)
+			}
+			if _, ok := srv.Attachments()[id]; ok {
+				t.Errorf("%s: %s is still on the REST API", tt.name, id)
+			}
+		}
+		srv.Close()
+	}
+}
+
+func TestAttachmentCollectorUnreferenced(t *testing.T) {
+	srv := signalmgrtest.NewServer(testNumber)
+	defer srv.Close()
+	srv.AddAttachment("seen", nil, "text/plain")
+	srv.AddAttachment("unseen", nil, "text/plain")
+	c := &signalmgr.AttachmentCollector{Client: srv.Client(), UnreferencedFor: 50 * time.Millisecond}
+	ctx := context.Background()
+
+	// Attachments never observed are aged from when they were first listed.
+	if report, _ := c.Collect(ctx); len(report.Deleted) != 0 {
+		t.Errorf("first run deleted %v", collected(report))
+	}
+	time.Sleep(60 * time.Millisecond)
+	c.Observe(withAttachments(map[string]time.Duration{"seen": time.Hour}))
+	report, err := c.Collect(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := collected(report); len(got) != 1 || got["unseen"] != signalmgr.CollectUnreferenced {
+		t.Errorf("deleted %v, want unseen", got)
+	}
+	if got := remaining(srv); !slices.Equal(got, []string{"seen"}) {
+		t.Errorf("remaining %v", got)
+	}
+}
+
+func TestAttachmentCollectorDryRunAndFailures(t *testing.T) {
+	srv := signalmgrtest.NewServer(testNumber)
+	defer srv.Close()
+	srv.AddAttachment("a", nil, "text/plain")
+	srv.AddAttachment("b", nil, "text/plain")
+	c := &signalmgr.AttachmentCollector{Client: srv.Client(), MaxAge: time.Minute, DryRun: true}
+	c.Observe(withAttachments(map[string]time.Duration{"a": time.Hour, "b": time.Hour}))
+	ctx := context.Background()
+
+	report, err := c.Collect(ctx)
+	if err != nil || !report.DryRun || len(report.Deleted) != 2 {
+		t.Errorf("dry run: got %+v, %v", report, err)
+	}
+	if got := remaining(srv); len(got) != 2 {
+		t.Errorf("dry run deleted attachments, remaining %v", got)
+	}
+
+	c.DryRun = false
+	srv.FailNext(http.MethodDelete, "/v1/attachments/a", http.StatusInternalServerError, "disk busy")
+	report, err = c.Collect(ctx)
+	if err == nil || !errors.Is(report.Err, err) {
+		t.Errorf("got %v, want the delete error", err)
+	}
+	if got := collected(report); len(got) != 1 || got["b"] != signalmgr.CollectMaxAge {
+		t.Errorf("deleted %v, want only b", got)
+	}
+	if got := remaining(srv); !slices.Equal(got, []string{"a"}) {
+		t.Errorf("remaining %v", got)
+	}
+
+	srv.FailNext(http.MethodGet, "/v1/attachments", http.StatusBadRequest, "unavailable")
+	if _, err := c.Collect(ctx); err == nil {
+		t.Error("a failed listing was not reported")
+	}
+}
+
+func TestAttachmentCollectorRun(t *testing.T) {
+	srv := signalmgrtest.NewServer(testNumber)
+	defer srv.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	reports := make(chan signalmgr.CollectReport, 16)
+	c := &signalmgr.AttachmentCollector{
+		Client:   srv.Client(),
+		Interval: 5 * time.Millisecond,
+		OnCollect: func(report signalmgr.CollectReport) {
+			select {
+			case reports <- report:
+			default:
+			}
+		},
+	}
+	done := make(chan error, 1)
+	go func() { done <- c.Run(ctx) }()
+	for range 2 {
+		select {
+		case <-reports:
+		case <-time.After(5 * time.Second):
+			t.Fatal("Run did not collect")
+		}
+	}
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("got %v, want context.Canceled", err)
+	}
+}
@@ -0,0 +1,165 @@
+package signalmgr
+
+import (
+	"context"
+	"encoding/base64"
+	"strings"
+)
+
+// Converts the group id found in received messages into the `group.` form used by the send endpoints and Group.ID.
+func groupRecipient(groupID string) string {
+	if strings.HasPrefix(groupID, "group.") {
+		return groupID
+	}
+	return "group." + base64.StdEncoding.EncodeToString([]byte(groupID))
+}
+
+// Returns the account the message was received by, using the client it was received with.
+func (m MessageResponse) account() *Account {
+	return &Account{Number: m.Account, Client: m.client}
+}
+
+// Returns the number of the sender, or their UUID if the number is hidden.
+func (m MessageResponse) Sender() string {
+	if m.Envelope.SourceNumber != "" {
+		return m.Envelope.SourceNumber
+	}
+	if m.Envelope.SourceUuid != "" {
+		return m.Envelope.SourceUuid
+	}
+	return m.Envelope.Source
+}
+
+// Returns the id of the group the message was sent in, as found in the envelope, or an empty string if it was not sent in a group.
+func (m MessageResponse) GroupID() string {
+	e := m.Envelope
+	switch {
+	case e.DataMessage.GroupInfo.GroupId != "":
+		return e.DataMessage.GroupInfo.GroupId
+	case e.EditMessage.DataMessage.GroupInfo.GroupId != "":
+		return e.EditMessage.DataMessage.GroupInfo.GroupId
+	case e.TypingMessag.GroupId != "":
+		return e.TypingMessag.GroupId
+	}
+	return ""
+}
+
+// Reports whether the message was sent in a group.
+func (m MessageResponse) IsGroup() bool {
+	return m.GroupID() != ""
+}
+
+// Returns the recipient to send to when replying: the group, in the `group.` form the send endpoints expect, for group messages and the sender otherwise.
+func (m MessageResponse) Conversation() string {
+	if groupID := m.GroupID(); groupID != "" {
+		return groupRecipient(groupID)
+	}
+	return m.Sender()
+}
+
+// Returns the sent timestamp of the message, which identifies it in quotes, reactions and receipts.
+//
+// For edits this is the timestamp of the message being edited.
+func (m MessageResponse) Timestamp() int64 {
+	e := m.Envelope
+	switch {
+	case e.EditMessage.TargetSentTimestamp != 0:
+		return e.EditMessage.TargetSentTimestamp
+	case e.DataMessage.Timestamp != 0:
+		return e.DataMessage.Timestamp
+	}
+	return e.Timestamp
+}
+
+// Returns the text of the message, including edited text.
+func (m MessageResponse) Text() string {
+	if m.Envelope.EditMessage.TargetSentTimestamp != 0 {
+		return m.Envelope.EditMessage.DataMessage.Message
+	}
+	return m.Envelope.DataMessage.Message
+}
+
+// Sends text to the conversation of the message.
+func (m MessageResponse) Reply(ctx context.Context, text string) (result SendResult, err error) {
+	return m.account().client().PostSendCtx(ctx, SendMessageV2{
+		Number:     m.Account,
+		Recipients: []string{m.Conversation()},
+		Message:    text,
+	})
+}
+
+// Sends text to the conversation of the message, quoting the message.
+func (m MessageResponse) ReplyQuoted(ctx context.Context, text string) (result SendResult, err error) {
+	timestamp := m.Timestamp()
+	author := m.Sender()
+	quoted := m.Text()
+	return m.account().client().PostSendCtx(ctx, SendMessageV2{
+		Number:         m.Account,
+		Recipients:     []string{m.Conversation()},
+		Message:        text,
+		QuoteTimestamp: &timestamp,
+		QuoteAuthor:    &author,
+		QuoteMessage:   &quoted,
+	})
+}
+
+// Reacts to the message with emoji.
+func (m MessageResponse) React(ctx context.Context, emoji string) error {
+	return m.account().PostReactionCtx(ctx, struct {
+		Reaction     string `json:"reaction"`
+		Recipient    string `json:"recipient"`
+		TargetAuthor string `json:"target_author"`
+		Timestamp    int64  `json:"timestamp"`
+	}{
+		Reaction:     emoji,
+		Recipient:    m.Conversation(),
+		TargetAuthor: m.Sender(),
+		Timestamp:    m.Timestamp(),
+	})
+}
+
+// Removes the reaction emoji from the message.
+func (m MessageResponse) Unreact(ctx context.Context, emoji string) error {
+	return m.account().DeleteReactionCtx(ctx, struct {
+		Reaction     string `json:"reaction"`
+		Recipient    string `json:"recipient"`
+		TargetAuthor string `json:"target_author"`
+		Timestamp    int64  `json:"timestamp"`
+	}{
+		Reaction:     emoji,
+		Recipient:    m.Conversation(),
+		TargetAuthor: m.Sender(),
+		Timestamp:    m.Timestamp(),
+	})
+}
+
+// Sends a read receipt for the message to its sender.
+func (m MessageResponse) MarkRead(ctx context.Context) error {
+	return m.account().PostReceiptsCtx(ctx, struct {
+		ReceiptType string `json:"receipt_type"`
+		Recipient   string `json:"recipient"`
+		Timestamp   int64  `json:"timestamp"`
+	}{
+		ReceiptType: "read",
+		Recipient:   m.Sender(),
+		Timestamp:   m.Timestamp(),
+	})
+}
+
+// Shows the typing indicator in the conversation of the message.
+func (m MessageResponse) ShowTyping(ctx context.Context) error {
+	return m.account().PutTypingIndicatorCtx(ctx, struct {
+		Recipient string `json:"recipient"`
+	}{
+		Recipient: m.Conversation(),
+	})
+}
+
+// Hides the typing indicator in the conversation of the message.
+func (m MessageResponse) HideTyping(ctx context.Context) error {
+	return m.account().DeleteTypingIndicatorCtx(ctx, struct {
+		Recipient string `json:"recipient"`
+	}{
+		Recipient: m.Conversation(),
+	})
+}
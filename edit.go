@@ -0,0 +1,67 @@
+package signalmgr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// How long after sending a message Signal accepts edits of it.
+var EditWindow = 24 * time.Hour
+
+// Returned by MessageBuilder.Build and Send when the message being edited was sent longer than EditWindow ago.
+var ErrEditWindowExpired = errors.New("edit window expired")
+
+// Starts an edit of our message original, to the same recipients. Add the new content, with styles and mentions, and call Send.
+//
+// Send fails if original was sent by another account, or with ErrEditWindowExpired if it is too old to edit.
+func (a *Account) EditMessage(original SendResult) *MessageBuilder {
+	b := a.NewMessage(original.Recipients...).Edit(original.target())
+	if original.Sender != "" && original.Sender != a.Number {
+		b.errs = append(b.errs, fmt.Errorf("cannot edit the message of %s from %s", original.Sender, a.Number))
+	}
+	return b
+}
+
+// Returns the author and sent timestamp of the message m replaces, if m is an edit. This includes edits we made from linked devices.
+func (m MessageResponse) EditTarget() (author string, timestamp int64, ok bool) {
+	e := m.Envelope
+	switch {
+	case e.EditMessage.TargetSentTimestamp != 0:
+		return m.Sender(), e.EditMessage.TargetSentTimestamp, true
+	case e.SyncMessage.SentMessage.EditMessage.TargetSentTimestamp != 0:
+		return m.Account, e.SyncMessage.SentMessage.EditMessage.TargetSentTimestamp, true
+	}
+	return "", 0, false
+}
+
+// Returns the message m edits from store. Returns an error matching ErrNotFound if m is not an edit or the message is not in the store.
+func (m MessageResponse) EditedMessage(ctx context.Context, store MessageStore) (StoredMessage, error) {
+	author, timestamp, ok := m.EditTarget()
+	if !ok {
+		return StoredMessage{}, fmt.Errorf("not an edit: %w", ErrNotFound)
+	}
+	return store.Get(ctx, m.Account, author, timestamp)
+}
+
+// Registers handler for edits, including those we made from linked devices, with the edited message looked up in store.
+//
+// original is nil if the edited message is not in the store. If the store records messages before handlers run, e.g. with RecordMiddleware, original already has the edit applied, and its replaced text is the last of its Edits.
+func (r *Router) OnEditOf(store MessageStore, handler func(ctx context.Context, m MessageResponse, original *StoredMessage) error) {
+	h := func(ctx context.Context, m MessageResponse) error {
+		if _, _, ok := m.EditTarget(); !ok {
+			return nil
+		}
+		original, err := m.EditedMessage(ctx, store)
+		if errors.Is(err, ErrNotFound) {
+			return handler(ctx, m, nil)
+		}
+		if err != nil {
+			return err
+		}
+		return handler(ctx, m, &original)
+	}
+	r.Handle(EventEdit, h)
+	r.Handle(EventSyncSent, h)
+}
@@ -0,0 +1,60 @@
+package signalmgr_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/DonovanDiamond/signalmgr/signalmgrtest"
+)
+
+func TestEditAndDelete(t *testing.T) {
+	srv := signalmgrtest.NewServer(testNumber)
+	defer srv.Close()
+	ctx := context.Background()
+
+	original, err := srv.Account(testNumber).NewMessage(otherNumber).Text("helo").Send(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edit, err := original.Edit().Text("hello").Send(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if edit.EditOf != original.Timestamp {
+		t.Errorf("edit of %d, want %d", edit.EditOf, original.Timestamp)
+	}
+	sent := srv.Sent()
+	if len(sent) != 2 || sent[1].EditTimestamp == nil || *sent[1].EditTimestamp != original.Timestamp || sent[1].Message != "hello" {
+		t.Fatalf("sent %+v", sent)
+	}
+	// Editing the edit still targets the original message.
+	if _, err := edit.Edit().Text("hello!").Send(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if sent := srv.Sent(); *sent[2].EditTimestamp != original.Timestamp {
+		t.Errorf("second edit targets %d, want %d", *sent[2].EditTimestamp, original.Timestamp)
+	}
+
+	if err := edit.Delete(ctx); err != nil {
+		t.Fatal(err)
+	}
+	requests := srv.RequestsTo(http.MethodDelete, "/v1/remote-delete/"+testNumber)
+	if len(requests) != 1 {
+		t.Fatalf("got %d delete requests, want 1", len(requests))
+	}
+	var body struct {
+		Recipient string `json:"recipient"`
+		Timestamp int64  `json:"timestamp"`
+	}
+	if err := requests[0].Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	if body.Recipient != otherNumber || body.Timestamp != original.Timestamp {
+		t.Errorf("deleted %+v, want %d for %s", body, original.Timestamp, otherNumber)
+	}
+
+	if _, err := srv.Account(otherNumber).EditMessage(original).Text("x").Build(); err == nil {
+		t.Error("edited another account's message")
+	}
+}
@@ -0,0 +1,78 @@
+package signalmgr
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Errors an *APIError can be matched against with errors.Is.
+var (
+	// The requested resource (group, attachment, contact, ...) does not exist.
+	ErrNotFound = errors.New("not found")
+	// Signal rate limited the account. The APIError's ChallengeTokens can be used with PostRateLimitChallenge.
+	ErrRateLimited = errors.New("rate limited")
+	// The identity of a recipient changed and is not trusted yet, see PutTrustIdentity.
+	ErrUntrustedIdentity = errors.New("untrusted identity")
+	// A recipient is not registered with Signal.
+	ErrUnregisteredUser = errors.New("unregistered user")
+	// The account the request was made for is not registered or linked on the REST API.
+	ErrAccountNotRegistered = errors.New("account not registered")
+)
+
+// An APIError is returned when the signal-cli-rest-api responds with an error or a non 2xx status.
+type APIError struct {
+	// HTTP status code of the response.
+	StatusCode int
+	// HTTP method of the request.
+	Method string
+	// Path of the request, including the query string.
+	Path string
+	// Error message from the response's JSON body. Empty if the body was not a JSON error.
+	Message string
+	// Challenge tokens included with rate limited send responses.
+	ChallengeTokens []string
+	// Raw response body.
+	Body []byte
+}
+
+func (e *APIError) Error() string {
+	if e.Message != "" {
+		return fmt.Sprintf("%s %s: status %d: %s", e.Method, e.Path, e.StatusCode, e.Message)
+	}
+	return fmt.Sprintf("%s %s: status %d and response received: %s", e.Method, e.Path, e.StatusCode, e.Body)
+}
+
+// Reports whether the error matches one of the ErrNotFound, ErrRateLimited, ErrUntrustedIdentity, ErrUnregisteredUser or ErrAccountNotRegistered sentinels.
+func (e *APIError) Is(target error) bool {
+	msg := strings.ToLower(e.Message)
+	switch target {
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound && !e.Is(ErrAccountNotRegistered)
+	case ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests ||
+			len(e.ChallengeTokens) > 0 ||
+			strings.Contains(msg, "rate limit") ||
+			strings.Contains(msg, "ratelimit") ||
+			strings.Contains(msg, "[429]")
+	case ErrUntrustedIdentity:
+		return strings.Contains(msg, "untrusted identit")
+	case ErrUnregisteredUser:
+		return strings.Contains(msg, "unregistered user") ||
+			strings.Contains(msg, "unregistered recipient")
+	case ErrAccountNotRegistered:
+		return strings.Contains(msg, "is not registered") ||
+			strings.Contains(msg, "account does not exist") ||
+			strings.Contains(msg, "no such account")
+	}
+	return false
+}
+
+// Returns the first challenge token of a rate limited response, or an empty string if there is none.
+func (e *APIError) ChallengeToken() string {
+	if len(e.ChallengeTokens) == 0 {
+		return ""
+	}
+	return e.ChallengeTokens[0]
+}
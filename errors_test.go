@@ -0,0 +1,42 @@
+package signalmgr_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/DonovanDiamond/signalmgr"
+)
+
+func TestAPIErrorIs(t *testing.T) {
+	sentinels := []error{
+		signalmgr.ErrNotFound,
+		signalmgr.ErrRateLimited,
+		signalmgr.ErrUntrustedIdentity,
+		signalmgr.ErrUnregisteredUser,
+		signalmgr.ErrAccountNotRegistered,
+	}
+	tests := []struct {
+		name string
+		err  signalmgr.APIError
+		// The sentinel the error matches, nil for none.
+		want error
+	}{
+		{"not found", signalmgr.APIError{StatusCode: 404, Message: "Group abc not found"}, signalmgr.ErrNotFound},
+		{"unregistered account with 404", signalmgr.APIError{StatusCode: 404, Message: "User +1555 is not registered."}, signalmgr.ErrAccountNotRegistered},
+		{"unregistered account", signalmgr.APIError{StatusCode: 400, Message: "User +1555 is not registered."}, signalmgr.ErrAccountNotRegistered},
+		{"no such account", signalmgr.APIError{StatusCode: 400, Message: "No such account"}, signalmgr.ErrAccountNotRegistered},
+		{"429", signalmgr.APIError{StatusCode: 429}, signalmgr.ErrRateLimited},
+		{"challenge", signalmgr.APIError{StatusCode: 400, ChallengeTokens: []string{"token"}}, signalmgr.ErrRateLimited},
+		{"rate limit message", signalmgr.APIError{StatusCode: 400, Message: "Failed to send message: [429] Rate Limit Exceeded"}, signalmgr.ErrRateLimited},
+		{"untrusted", signalmgr.APIError{StatusCode: 400, Message: "Untrusted Identity for \"+1555\""}, signalmgr.ErrUntrustedIdentity},
+		{"unregistered user", signalmgr.APIError{StatusCode: 400, Message: "Failed to send message: Unregistered user \"+1555\""}, signalmgr.ErrUnregisteredUser},
+		{"other", signalmgr.APIError{StatusCode: 500, Message: "internal error"}, nil},
+	}
+	for _, tt := range tests {
+		for _, sentinel := range sentinels {
+			if got := errors.Is(&tt.err, sentinel); got != (sentinel == tt.want) {
+				t.Errorf("%s: errors.Is(%v, %v) = %v", tt.name, &tt.err, sentinel, got)
+			}
+		}
+	}
+}
@@ -0,0 +1,232 @@
+// Package fibertransport provides a signalmgr.Transport that sends requests with fiber, for programs that already use fiber's client:
+//
+//	client := signalmgr.NewClient("http://127.0.0.1:8080")
+//	client.Transport = fibertransport.New(nil)
+package fibertransport
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/DonovanDiamond/signalmgr"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gorilla/websocket"
+	"github.com/valyala/fasthttp"
+)
+
+// A Transport sends requests with fiber and opens websockets with gorilla/websocket.
+type Transport struct {
+	// Fiber client used to create requests. If nil, fiber's default client is used.
+	Client *fiber.Client
+	// TLS configuration used for https and wss URLs.
+	TLSConfig *tls.Config
+	// Timeout for each read of a streamed response body. Zero means no timeout.
+	ReadTimeout time.Duration
+	// Dialer used to open websockets. If nil, websocket.DefaultDialer is used.
+	Dialer *websocket.Dialer
+}
+
+// Creates a Transport using client, or fiber's default client if nil.
+func New(client *fiber.Client) *Transport {
+	return &Transport{Client: client}
+}
+
+// Creates a fiber agent for req with the transport's settings applied.
+//
+// The request timeout is the time left until ctx's deadline. Returns an error if fiber cannot parse req.URL or req.Method is not GET, POST, PUT or DELETE.
+func (t *Transport) agent(ctx context.Context, req *signalmgr.Request) (*fiber.Agent, error) {
+	var a *fiber.Agent
+	if t.Client != nil {
+		switch req.Method {
+		case fiber.MethodPost:
+			a = t.Client.Post(req.URL)
+		case fiber.MethodPut:
+			a = t.Client.Put(req.URL)
+		case fiber.MethodDelete:
+			a = t.Client.Delete(req.URL)
+		case fiber.MethodGet:
+			a = t.Client.Get(req.URL)
+		default:
+			return nil, fmt.Errorf("unsupported request method %s", req.Method)
+		}
+	} else {
+		switch req.Method {
+		case fiber.MethodPost:
+			a = fiber.Post(req.URL)
+		case fiber.MethodPut:
+			a = fiber.Put(req.URL)
+		case fiber.MethodDelete:
+			a = fiber.Delete(req.URL)
+		case fiber.MethodGet:
+			a = fiber.Get(req.URL)
+		default:
+			return nil, fmt.Errorf("unsupported request method %s", req.Method)
+		}
+	}
+	if a.HostClient == nil {
+		// Fiber only creates the host client once the URL parsed, and keeps the parse error until the request is sent.
+		_, _, errs := a.Bytes()
+		return nil, fmt.Errorf("invalid request URL: %w", errors.Join(errs...))
+	}
+	for key := range req.Header {
+		a.Set(key, req.Header.Get(key))
+	}
+	if req.Body != nil {
+		a.Body(req.Body)
+	}
+	if t.TLSConfig != nil {
+		a.TLSConfig(t.TLSConfig)
+	}
+	if deadline, ok := ctx.Deadline(); ok && !req.Stream {
+		a.Timeout(max(time.Until(deadline), time.Millisecond))
+	}
+	return a, nil
+}
+
+func (t *Transport) RoundTrip(ctx context.Context, req *signalmgr.Request) (*signalmgr.Response, error) {
+	if req.Stream {
+		return t.stream(ctx, req)
+	}
+	a, err := t.agent(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	resp := fiber.AcquireResponse()
+	a.SetResponse(resp)
+	type result struct {
+		status int
+		body   []byte
+		errs   []error
+	}
+	done := make(chan result, 1)
+	go func() {
+		status, body, errs := a.Bytes()
+		done <- result{status, body, errs}
+	}()
+	var r result
+	select {
+	case <-ctx.Done():
+		go func() {
+			<-done
+			fiber.ReleaseResponse(resp)
+		}()
+		return nil, ctx.Err()
+	case r = <-done:
+	}
+	defer fiber.ReleaseResponse(resp)
+	if len(r.errs) > 0 && ctx.Err() != nil {
+		// The request timeout is derived from ctx's deadline, so report it as such.
+		return nil, ctx.Err()
+	}
+	if len(r.errs) > 0 {
+		return nil, fmt.Errorf("%d errors on post request: %+v", len(r.errs), r.errs)
+	}
+	return &signalmgr.Response{
+		StatusCode: r.status,
+		Header:     responseHeader(&resp.Header),
+		Body:       io.NopCloser(bytes.NewReader(r.body)),
+	}, nil
+}
+
+func responseHeader(h *fasthttp.ResponseHeader) http.Header {
+	header := http.Header{}
+	h.VisitAll(func(key, value []byte) {
+		header.Add(string(key), string(value))
+	})
+	return header
+}
+
+// Sends req without reading the response body.
+func (t *Transport) stream(ctx context.Context, req *signalmgr.Request) (*signalmgr.Response, error) {
+	a, err := t.agent(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	a.HostClient.StreamResponseBody = true
+	a.HostClient.ReadTimeout = t.ReadTimeout
+	resp := fiber.AcquireResponse()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- a.HostClient.Do(a.Request(), resp)
+	}()
+	select {
+	case <-ctx.Done():
+		go func() {
+			<-done
+			resp.CloseBodyStream()
+			fiber.ReleaseResponse(resp)
+			fiber.ReleaseAgent(a)
+		}()
+		return nil, ctx.Err()
+	case err = <-done:
+	}
+	host := a.HostClient
+	fiber.ReleaseAgent(a)
+
+	body := &streamBody{host: host, resp: resp, reader: resp.BodyStream()}
+	if body.reader == nil {
+		body.reader = bytes.NewReader(resp.Body())
+	}
+	if err != nil {
+		body.Close()
+		return nil, err
+	}
+	return &signalmgr.Response{
+		StatusCode: resp.StatusCode(),
+		Header:     responseHeader(&resp.Header),
+		Body:       body,
+	}, nil
+}
+
+// A streamed fiber response body.
+type streamBody struct {
+	host   *fasthttp.HostClient
+	resp   *fiber.Response
+	reader io.Reader
+	closed bool
+}
+
+func (b *streamBody) Read(p []byte) (int, error) {
+	return b.reader.Read(p)
+}
+
+func (b *streamBody) Close() error {
+	if b.closed {
+		return nil
+	}
+	b.closed = true
+	err := b.resp.CloseBodyStream()
+	fiber.ReleaseResponse(b.resp)
+	// The connection may still hold unread data, so it is not reused.
+	b.host.CloseIdleConnections()
+	return err
+}
+
+// Opens a websocket to req.URL, using the transport's dialer and TLS configuration.
+func (t *Transport) Dial(ctx context.Context, req *signalmgr.Request) (signalmgr.Conn, error) {
+	url := strings.Replace(req.URL, "https://", "wss://", 1)
+	url = strings.Replace(url, "http://", "ws://", 1)
+
+	dialer := websocket.DefaultDialer
+	if t.Dialer != nil {
+		dialer = t.Dialer
+	}
+	if t.TLSConfig != nil && dialer.TLSClientConfig == nil {
+		d := *dialer
+		d.TLSClientConfig = t.TLSConfig
+		dialer = &d
+	}
+	conn, resp, err := dialer.DialContext(ctx, url, req.Header)
+	if err != nil {
+		return nil, signalmgr.HandshakeError(req, resp, err)
+	}
+	return conn, nil
+}
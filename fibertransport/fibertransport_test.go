@@ -0,0 +1,32 @@
+package fibertransport
+
+import (
+	"context"
+	"crypto/tls"
+	"strings"
+	"testing"
+
+	"github.com/DonovanDiamond/signalmgr"
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestRoundTripInvalidURL(t *testing.T) {
+	tr := &Transport{TLSConfig: &tls.Config{}}
+	for _, stream := range []bool{false, true} {
+		_, err := tr.RoundTrip(context.Background(), &signalmgr.Request{Method: "GET", URL: "ftp://signal/v1/about", Stream: stream})
+		if err == nil || !strings.Contains(err.Error(), "unsupported protocol") {
+			t.Errorf("stream %v: got error %v, want unsupported protocol", stream, err)
+		}
+	}
+}
+
+func TestRoundTripUnsupportedMethod(t *testing.T) {
+	for _, tr := range []*Transport{New(nil), New(fiber.AcquireClient())} {
+		for _, stream := range []bool{false, true} {
+			_, err := tr.RoundTrip(context.Background(), &signalmgr.Request{Method: "PATCH", URL: "http://signal/v1/about", Stream: stream})
+			if err == nil || !strings.Contains(err.Error(), "unsupported request method PATCH") {
+				t.Errorf("stream %v: got error %v, want unsupported request method", stream, err)
+			}
+		}
+	}
+}
@@ -1,8 +1,9 @@
 package signalmgr
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
+	"net/url"
 )
 
 type GetAboutResponse struct {
@@ -16,15 +17,45 @@ type GetAboutResponse struct {
 // List all accounts.
 //
 // Lists all of the accounts linked or registered.
+func (c *Client) GetAbout() (resp GetAboutResponse, err error) {
+	return c.GetAboutCtx(context.Background())
+}
+
+// Same as GetAbout, but uses ctx for cancellation and deadlines.
+func (c *Client) GetAboutCtx(ctx context.Context) (resp GetAboutResponse, err error) {
+	return get[GetAboutResponse](ctx, c, "/v1/about")
+}
+
+// Calls GetAbout on DefaultClient.
 func GetAbout() (resp GetAboutResponse, err error) {
-	return get[GetAboutResponse]("/v1/about")
+	return GetAboutCtx(context.Background())
+}
+
+// Calls GetAboutCtx on DefaultClient.
+func GetAboutCtx(ctx context.Context) (resp GetAboutResponse, err error) {
+	return DefaultClient.GetAboutCtx(ctx)
 }
 
 // API Health Check.
 //
 // Internally used by the docker container to perform the health check.
+func (c *Client) GetHealth() (resp string, err error) {
+	return c.GetHealthCtx(context.Background())
+}
+
+// Same as GetHealth, but uses ctx for cancellation and deadlines.
+func (c *Client) GetHealthCtx(ctx context.Context) (resp string, err error) {
+	return get[string](ctx, c, "/v1/health")
+}
+
+// Calls GetHealth on DefaultClient.
 func GetHealth() (resp string, err error) {
-	return get[string]("/v1/health")
+	return GetHealthCtx(context.Background())
+}
+
+// Calls GetHealthCtx on DefaultClient.
+func GetHealthCtx(ctx context.Context) (resp string, err error) {
+	return DefaultClient.GetHealthCtx(ctx)
 }
 
 type Configuration struct {
@@ -34,19 +65,66 @@ type Configuration struct {
 }
 
 // List the REST API configuration.
+func (c *Client) GetConfiguration() (resp Configuration, err error) {
+	return c.GetConfigurationCtx(context.Background())
+}
+
+// Same as GetConfiguration, but uses ctx for cancellation and deadlines.
+func (c *Client) GetConfigurationCtx(ctx context.Context) (resp Configuration, err error) {
+	return get[Configuration](ctx, c, "/v1/about")
+}
+
+// Calls GetConfiguration on DefaultClient.
 func GetConfiguration() (resp Configuration, err error) {
-	return get[Configuration]("/v1/about")
+	return GetConfigurationCtx(context.Background())
+}
+
+// Calls GetConfigurationCtx on DefaultClient.
+func GetConfigurationCtx(ctx context.Context) (resp Configuration, err error) {
+	return DefaultClient.GetConfigurationCtx(ctx)
 }
 
 // Set the REST API configuration.
-func PostConfiguration(data Configuration) (err error) {
-	_, err = post[any]("/v1/configuration", data)
+func (c *Client) PostConfiguration(data Configuration) (err error) {
+	return c.PostConfigurationCtx(context.Background(), data)
+}
+
+// Same as PostConfiguration, but uses ctx for cancellation and deadlines.
+func (c *Client) PostConfigurationCtx(ctx context.Context, data Configuration) (err error) {
+	_, err = post[any](ctx, c, "/v1/configuration", data)
 	return
 }
 
+// Calls PostConfiguration on DefaultClient.
+func PostConfiguration(data Configuration) (err error) {
+	return PostConfigurationCtx(context.Background(), data)
+}
+
+// Calls PostConfigurationCtx on DefaultClient.
+func PostConfigurationCtx(ctx context.Context, data Configuration) (err error) {
+	return DefaultClient.PostConfigurationCtx(ctx, data)
+}
+
 // Link device and generate QR code.
-func GetLinkAccountQRCode(deviceName string) (link string, err error) {
-	return get[string]("/v1/qrcodelink?" + encodeParams(params{"device_name": deviceName}))
+//
+// Returns the QR code as PNG image data. It used to be returned as a string, which the REST API never sent.
+func (c *Client) GetLinkAccountQRCode(deviceName string) (png []byte, err error) {
+	return c.GetLinkAccountQRCodeCtx(context.Background(), deviceName)
+}
+
+// Same as GetLinkAccountQRCode, but uses ctx for cancellation and deadlines.
+func (c *Client) GetLinkAccountQRCodeCtx(ctx context.Context, deviceName string) (png []byte, err error) {
+	return getRaw(ctx, c, "/v1/qrcodelink?"+encodeParams(params{"device_name": deviceName}))
+}
+
+// Calls GetLinkAccountQRCode on DefaultClient.
+func GetLinkAccountQRCode(deviceName string) (png []byte, err error) {
+	return GetLinkAccountQRCodeCtx(context.Background(), deviceName)
+}
+
+// Calls GetLinkAccountQRCodeCtx on DefaultClient.
+func GetLinkAccountQRCodeCtx(ctx context.Context, deviceName string) (png []byte, err error) {
+	return DefaultClient.GetLinkAccountQRCodeCtx(ctx, deviceName)
 }
 
 type SendMessageV2_MessageMention struct {
@@ -74,48 +152,119 @@ type SendMessageV2 struct {
 // Send a signal message.
 //
 // Send a signal message. Set the text_mode to 'styled' in case you want to add formatting to your text message. Styling Options: *italic text*, **bold text**, ~strikethrough text~.
-func PostSend(data SendMessageV2) (resp struct {
-	Timestamp string `json:"timestamp"`
-}, err error) {
-	return post[struct {
-		Timestamp string `json:"timestamp"`
-	}]("/v2/send", data)
+func (c *Client) PostSend(data SendMessageV2) (result SendResult, err error) {
+	return c.PostSendCtx(context.Background(), data)
+}
+
+// Same as PostSend, but uses ctx for cancellation and deadlines.
+func (c *Client) PostSendCtx(ctx context.Context, data SendMessageV2) (result SendResult, err error) {
+	result, err = post[SendResult](ctx, c, "/v2/send", data)
+	if err != nil {
+		return
+	}
+	result.sent(c, data)
+	return
+}
+
+// Calls PostSend on DefaultClient.
+func PostSend(data SendMessageV2) (result SendResult, err error) {
+	return PostSendCtx(context.Background(), data)
+}
+
+// Calls PostSendCtx on DefaultClient.
+func PostSendCtx(ctx context.Context, data SendMessageV2) (result SendResult, err error) {
+	return DefaultClient.PostSendCtx(ctx, data)
 }
 
 // List all attachments.
 //
 // List all downloaded attachments.
+func (c *Client) GetAttachments() (attachments []string, err error) {
+	return c.GetAttachmentsCtx(context.Background())
+}
+
+// Same as GetAttachments, but uses ctx for cancellation and deadlines.
+func (c *Client) GetAttachmentsCtx(ctx context.Context) (attachments []string, err error) {
+	return get[[]string](ctx, c, "/v1/attachments")
+}
+
+// Calls GetAttachments on DefaultClient.
 func GetAttachments() (attachments []string, err error) {
-	return get[[]string]("/v1/attachments")
+	return GetAttachmentsCtx(context.Background())
+}
+
+// Calls GetAttachmentsCtx on DefaultClient.
+func GetAttachmentsCtx(ctx context.Context) (attachments []string, err error) {
+	return DefaultClient.GetAttachmentsCtx(ctx)
 }
 
 // Serve Attachment.
 //
 // Serve the attachment with the given id.
+func (c *Client) GetAttachment(id string) (raw []byte, err error) {
+	return c.GetAttachmentCtx(context.Background(), id)
+}
+
+// Same as GetAttachment, but uses ctx for cancellation and deadlines.
+func (c *Client) GetAttachmentCtx(ctx context.Context, id string) (raw []byte, err error) {
+	return getRaw(ctx, c, fmt.Sprintf("/v1/attachments/%s", id))
+}
+
+// Calls GetAttachment on DefaultClient.
 func GetAttachment(id string) (raw []byte, err error) {
-	return getRaw(fmt.Sprintf("/v1/attachments/%s", id))
+	return GetAttachmentCtx(context.Background(), id)
+}
+
+// Calls GetAttachmentCtx on DefaultClient.
+func GetAttachmentCtx(ctx context.Context, id string) (raw []byte, err error) {
+	return DefaultClient.GetAttachmentCtx(ctx, id)
 }
 
 // Remove attachment.
 //
 // Remove the attachment with the given id from filesystem.
-func DeleteAttachment(id string) (err error) {
-	_, err = delete[any](fmt.Sprintf("/v1/attachments/%s", id), nil)
+func (c *Client) DeleteAttachment(id string) (err error) {
+	return c.DeleteAttachmentCtx(context.Background(), id)
+}
+
+// Same as DeleteAttachment, but uses ctx for cancellation and deadlines.
+func (c *Client) DeleteAttachmentCtx(ctx context.Context, id string) (err error) {
+	_, err = del[any](ctx, c, fmt.Sprintf("/v1/attachments/%s", id), nil)
 	return
 }
 
+// Calls DeleteAttachment on DefaultClient.
+func DeleteAttachment(id string) (err error) {
+	return DeleteAttachmentCtx(context.Background(), id)
+}
+
+// Calls DeleteAttachmentCtx on DefaultClient.
+func DeleteAttachmentCtx(ctx context.Context, id string) (err error) {
+	return DefaultClient.DeleteAttachmentCtx(ctx, id)
+}
+
 type SearchResult struct {
 	Number     string `json:"number"`
 	Registered bool   `json:"registered"`
 }
 
 // Check if one or more phone numbers are registered with the Signal Service.
+func (c *Client) GetSearch(numbers []string) (results []SearchResult, err error) {
+	return c.GetSearchCtx(context.Background(), numbers)
+}
+
+// Same as GetSearch, but uses ctx for cancellation and deadlines.
+func (c *Client) GetSearchCtx(ctx context.Context, numbers []string) (results []SearchResult, err error) {
+	query := url.Values{"numbers": numbers}
+	return get[[]SearchResult](ctx, c, "/v1/search?"+query.Encode())
+}
+
+// Calls GetSearch on DefaultClient.
 func GetSearch(numbers []string) (results []SearchResult, err error) {
-	numbersJSON, err := json.Marshal(numbers)
-	if err != nil {
-		return
-	}
-	return get[[]SearchResult]("/v1/search?" + encodeParams(params{
-		"message": string(numbersJSON),
-	}))
+	return GetSearchCtx(context.Background(), numbers)
+}
+
+// Calls GetSearchCtx on DefaultClient.
+func GetSearchCtx(ctx context.Context, numbers []string) (results []SearchResult, err error) {
+	return DefaultClient.GetSearchCtx(ctx, numbers)
 }
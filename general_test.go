@@ -0,0 +1,39 @@
+package signalmgr_test
+
+import (
+	"bytes"
+	"context"
+	"image/png"
+	"net/http"
+	"testing"
+
+	"github.com/DonovanDiamond/signalmgr/signalmgrtest"
+)
+
+func TestSearchAndQRCode(t *testing.T) {
+	srv := signalmgrtest.NewServer("+14155550123")
+	defer srv.Close()
+	ctx := context.Background()
+
+	results, err := srv.Client().GetSearchCtx(ctx, []string{"+14155550123", "+14155550111"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 || !results[0].Registered || results[1].Registered {
+		t.Errorf("got %+v", results)
+	}
+	if requests := srv.RequestsTo(http.MethodGet, "/v1/search"); len(requests) != 1 || requests[0].Query != "numbers=%2B14155550123&numbers=%2B14155550111" {
+		t.Errorf("got search requests %+v", requests)
+	}
+
+	code, err := srv.Client().GetLinkAccountQRCodeCtx(ctx, "bot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := png.Decode(bytes.NewReader(code)); err != nil {
+		t.Errorf("QR code is not a PNG: %v", err)
+	}
+	if _, err := srv.Client().GetLinkAccountQRCodeCtx(ctx, ""); err == nil {
+		t.Error("got a QR code without a device name")
+	}
+}
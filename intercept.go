@@ -0,0 +1,75 @@
+package signalmgr
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"slices"
+	"strings"
+)
+
+// Sends a request and returns its response.
+type RoundTripFunc func(ctx context.Context, req *Request) (*Response, error)
+
+// Wraps the sending of every request, e.g. to log, measure or modify requests and responses. Interceptors may return a response without calling next.
+type Interceptor func(next RoundTripFunc) RoundTripFunc
+
+// Sends req through the client's interceptors and transport, in a span if the client has a Tracer.
+//
+// If buffer is set, the response body is read in full before the interceptors see it, so they observe the whole request and may read the body.
+func (c *Client) roundTrip(ctx context.Context, req *Request, buffer bool) (*Response, error) {
+	transport := c.transport()
+	rt := func(ctx context.Context, req *Request) (*Response, error) {
+		resp, err := transport.RoundTrip(ctx, req)
+		if err != nil || !buffer {
+			return resp, err
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return nil, err
+		}
+		resp.Body = io.NopCloser(bytes.NewReader(body))
+		return resp, nil
+	}
+	for _, interceptor := range slices.Backward(c.Interceptors) {
+		rt = interceptor(rt)
+	}
+	if c.Tracer == nil {
+		return rt(ctx, req)
+	}
+	ctx, span := c.startRequestSpan(ctx, req)
+	resp, err := rt(ctx, req)
+	endRequestSpan(span, resp, err)
+	return resp, err
+}
+
+// Wraps send, which delivers a message read from a receive websocket, in the client's ReceiveMiddleware.
+func (c *Client) receiveHandler(send HandlerFunc) HandlerFunc {
+	for _, m := range slices.Backward(c.ReceiveMiddleware) {
+		send = m(send)
+	}
+	return send
+}
+
+// Path segments of the REST API routes that are not parameters.
+var routeWords = []string{
+	"admins", "block", "join", "members", "quit", "rate-limit-challenge", "settings", "sync", "trust", "username", "verify",
+}
+
+// Returns the route of path, with parameters replaced by {number} for phone numbers or {id} otherwise, e.g. "/v1/groups/{number}/{id}/members".
+func routeTemplate(path string) string {
+	path, _, _ = strings.Cut(path, "?")
+	segments := strings.Split(path, "/")
+	// Segments are "", the version and the resource, followed by the parameters.
+	for i := 3; i < len(segments); i++ {
+		switch {
+		case slices.Contains(routeWords, segments[i]):
+		case numberPattern.FindString(segments[i]) == segments[i]:
+			segments[i] = "{number}"
+		default:
+			segments[i] = "{id}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
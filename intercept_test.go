@@ -0,0 +1,29 @@
+package signalmgr
+
+import "testing"
+
+func TestRouteTemplate(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/v1/about", "/v1/about"},
+		{"/v2/send", "/v2/send"},
+		{"/v1/groups/+14155550123", "/v1/groups/{number}"},
+		{"/v1/groups/+14155550123/group.abc==/members", "/v1/groups/{number}/{id}/members"},
+		{"/v1/groups/+14155550123/group.abc==", "/v1/groups/{number}/{id}"},
+		{"/v1/identities/+14155550123/trust/+14155550199", "/v1/identities/{number}/trust/{number}"},
+		{"/v1/register/+14155550123/verify/123-456", "/v1/register/{number}/verify/{id}"},
+		{"/v1/accounts/+14155550123/rate-limit-challenge", "/v1/accounts/{number}/rate-limit-challenge"},
+		{"/v1/attachments/abc.jpg", "/v1/attachments/{id}"},
+		{"/v1/search?numbers=%2B14155550123", "/v1/search"},
+		{"/v1/qrcodelink?device_name=bot", "/v1/qrcodelink"},
+		// A number followed by more characters is not a number.
+		{"/v1/profiles/+14155550123x", "/v1/profiles/{id}"},
+	}
+	for _, tt := range tests {
+		if got := routeTemplate(tt.path); got != tt.want {
+			t.Errorf("routeTemplate(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
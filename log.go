@@ -0,0 +1,86 @@
+package signalmgr
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// A LogInterceptor logs requests and received messages with log/slog, with phone numbers redacted.
+//
+// Use its Intercept method in Client.Interceptors and its Receive method in Client.ReceiveMiddleware or Router.Use.
+type LogInterceptor struct {
+	// If nil, slog.Default() is used.
+	Logger *slog.Logger
+	// Redacts numbers in paths and errors. If nil, DefaultRedactor is used.
+	Redactor *Redactor
+	// Level of successful requests and received messages. Failed requests are logged at slog.LevelWarn.
+	Level slog.Level
+}
+
+// Creates a LogInterceptor logging to logger at debug level.
+func NewLogInterceptor(logger *slog.Logger) *LogInterceptor {
+	return &LogInterceptor{Logger: logger, Level: slog.LevelDebug}
+}
+
+func (l *LogInterceptor) logger() *slog.Logger {
+	if l.Logger == nil {
+		return slog.Default()
+	}
+	return l.Logger
+}
+
+func (l *LogInterceptor) redactor() *Redactor {
+	if l.Redactor == nil {
+		return DefaultRedactor
+	}
+	return l.Redactor
+}
+
+// Logs each request with its method, route, redacted path, status and duration.
+func (l *LogInterceptor) Intercept(next RoundTripFunc) RoundTripFunc {
+	return func(ctx context.Context, req *Request) (*Response, error) {
+		start := time.Now()
+		resp, err := next(ctx, req)
+		attrs := []slog.Attr{
+			slog.String("method", req.Method),
+			slog.String("route", req.Route),
+			slog.String("path", l.redactor().text(req.Path)),
+			slog.Duration("duration", time.Since(start)),
+		}
+		level := l.Level
+		if err != nil {
+			level = slog.LevelWarn
+			attrs = append(attrs, slog.String("error", l.redactor().text(err.Error())))
+		} else {
+			attrs = append(attrs, slog.Int("status", resp.StatusCode))
+			if resp.StatusCode < 200 || resp.StatusCode > 299 {
+				level = slog.LevelWarn
+			}
+		}
+		l.logger().LogAttrs(ctx, level, "signal api request", attrs...)
+		return resp, err
+	}
+}
+
+// Logs each received message with its redacted account and sender, kind and timestamp, and how long next took.
+func (l *LogInterceptor) Receive(next HandlerFunc) HandlerFunc {
+	return func(ctx context.Context, m MessageResponse) error {
+		start := time.Now()
+		err := next(ctx, m)
+		attrs := []slog.Attr{
+			slog.String("account", l.redactor().text(m.Account)),
+			slog.String("sender", l.redactor().text(m.Sender())),
+			slog.String("kind", m.Kind().String()),
+			slog.Int64("timestamp", m.Timestamp()),
+			slog.Duration("duration", time.Since(start)),
+		}
+		level := l.Level
+		if err != nil && ctx.Err() == nil {
+			level = slog.LevelWarn
+			attrs = append(attrs, slog.String("error", l.redactor().text(err.Error())))
+		}
+		l.logger().LogAttrs(ctx, level, "signal message received", attrs...)
+		return err
+	}
+}
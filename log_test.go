@@ -0,0 +1,61 @@
+package signalmgr_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/DonovanDiamond/signalmgr"
+	"github.com/DonovanDiamond/signalmgr/signalmgrtest"
+)
+
+func TestLogInterceptor(t *testing.T) {
+	srv := signalmgrtest.NewServer(testNumber)
+	defer srv.Close()
+	var out bytes.Buffer
+	logger := signalmgr.NewLogInterceptor(slog.New(slog.NewTextHandler(&out, &slog.HandlerOptions{Level: slog.LevelDebug})))
+	client := srv.Client()
+	client.Retry = &signalmgr.RetryPolicy{}
+	client.Interceptors = []signalmgr.Interceptor{logger.Intercept}
+	ctx := context.Background()
+
+	if _, err := client.GetSearchCtx(ctx, []string{otherNumber}); err != nil {
+		t.Fatal(err)
+	}
+	srv.FailNext(http.MethodGet, "/v1/groups/"+testNumber, http.StatusBadRequest, "unknown account "+testNumber)
+	if _, err := (&signalmgr.Account{Number: testNumber, Client: client}).GetGroupsCtx(ctx); err == nil {
+		t.Fatal("the failed request succeeded")
+	}
+	handler := logger.Receive(func(ctx context.Context, m signalmgr.MessageResponse) error {
+		return errors.New("no reply to " + m.Sender())
+	})
+	handler(ctx, signalmgr.MessageResponse{Envelope: signalmgrtest.TextEnvelope(otherNumber, "hi"), Account: testNumber})
+
+	log := out.String()
+	for _, number := range []string{testNumber, otherNumber, url.QueryEscape(otherNumber)} {
+		if strings.Contains(log, number) {
+			t.Errorf("log contains %s:\n%s", number, log)
+		}
+	}
+	lines := strings.Split(strings.TrimSpace(log), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("got %d lines, want 3:\n%s", len(lines), log)
+	}
+	for i, want := range []string{
+		"level=DEBUG msg=\"signal api request\" method=GET route=/v1/search path=\"/v1/search?numbers=%2B1555",
+		"level=WARN msg=\"signal api request\" method=GET route=/v1/groups/{number} path=/v1/groups/+1555",
+		"level=WARN msg=\"signal message received\" account=+1555",
+	} {
+		if !strings.Contains(lines[i], want) {
+			t.Errorf("line %d is %s, want it to contain %s", i+1, lines[i], want)
+		}
+	}
+	if !strings.Contains(lines[1], "status=400") || !strings.Contains(lines[2], `error="no reply to +1555`) {
+		t.Errorf("failures are not logged:\n%s", log)
+	}
+}
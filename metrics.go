@@ -0,0 +1,258 @@
+package signalmgr
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"maps"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Metrics records counters and histograms, e.g. in Prometheus or OpenTelemetry.
+//
+// Implementations must be safe for concurrent use.
+type Metrics interface {
+	// Adds delta to the counter name with labels.
+	Count(name string, labels map[string]string, delta float64)
+	// Records value in the histogram name with labels.
+	Observe(name string, labels map[string]string, value float64)
+}
+
+// Names of the metrics recorded by a MetricsInterceptor.
+const (
+	// Counter of requests, labelled by method, route and status. Status is "error" for requests that got no response.
+	MetricRequests = "signalmgr_requests_total"
+	// Histogram of request durations in seconds, labelled by method and route.
+	MetricRequestDuration = "signalmgr_request_duration_seconds"
+	// Counter of received messages, labelled by kind.
+	MetricMessages = "signalmgr_messages_received_total"
+	// Histogram of how long received messages took to be handled in seconds, labelled by kind.
+	MetricMessageDuration = "signalmgr_message_duration_seconds"
+)
+
+// A MetricsInterceptor records requests and received messages in Metrics.
+//
+// Use its Intercept method in Client.Interceptors and its Receive method in Client.ReceiveMiddleware or Router.Use.
+type MetricsInterceptor struct {
+	Metrics Metrics
+}
+
+// Creates a MetricsInterceptor recording in metrics.
+func NewMetricsInterceptor(metrics Metrics) *MetricsInterceptor {
+	return &MetricsInterceptor{Metrics: metrics}
+}
+
+// Counts each request by method, route and status, and records its duration.
+func (i *MetricsInterceptor) Intercept(next RoundTripFunc) RoundTripFunc {
+	return func(ctx context.Context, req *Request) (*Response, error) {
+		start := time.Now()
+		resp, err := next(ctx, req)
+		status := "error"
+		if err == nil {
+			status = strconv.Itoa(resp.StatusCode)
+		}
+		i.Metrics.Count(MetricRequests, map[string]string{"method": req.Method, "route": req.Route, "status": status}, 1)
+		i.Metrics.Observe(MetricRequestDuration, map[string]string{"method": req.Method, "route": req.Route}, time.Since(start).Seconds())
+		return resp, err
+	}
+}
+
+// Counts each received message by kind, and records how long next took.
+func (i *MetricsInterceptor) Receive(next HandlerFunc) HandlerFunc {
+	return func(ctx context.Context, m MessageResponse) error {
+		start := time.Now()
+		err := next(ctx, m)
+		labels := map[string]string{"kind": m.Kind().String()}
+		i.Metrics.Count(MetricMessages, labels, 1)
+		i.Metrics.Observe(MetricMessageDuration, labels, time.Since(start).Seconds())
+		return err
+	}
+}
+
+// Upper bounds of the histogram buckets of MemoryMetrics, in seconds.
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// MemoryMetrics keeps Metrics in memory, and serves them in the Prometheus text exposition format.
+//
+// The zero value is ready to use. MemoryMetrics are safe for concurrent use.
+type MemoryMetrics struct {
+	// Upper bounds of the histogram buckets, sorted. If nil, DefaultBuckets are used. Must not change once a value was observed.
+	Buckets []float64
+
+	mu         sync.Mutex
+	counters   map[string]map[string]*metricSeries
+	histograms map[string]map[string]*metricSeries
+}
+
+// A counter or histogram with one set of labels.
+type metricSeries struct {
+	labels map[string]string
+	// Value of a counter, or sum of a histogram.
+	value float64
+	count uint64
+	// Counts of the histogram's values per bucket, not cumulative.
+	buckets []uint64
+}
+
+// A snapshot of a histogram.
+type Histogram struct {
+	Count uint64
+	Sum   float64
+	// Upper bounds of the buckets.
+	Bounds []float64
+	// Number of values up to each bound, cumulative.
+	Buckets []uint64
+}
+
+// Creates empty MemoryMetrics.
+func NewMemoryMetrics() *MemoryMetrics {
+	return &MemoryMetrics{}
+}
+
+func (m *MemoryMetrics) bounds() []float64 {
+	if m.Buckets == nil {
+		return DefaultBuckets
+	}
+	return m.Buckets
+}
+
+// Returns the series of name with labels in metrics, creating it if needed.
+func series(metrics map[string]map[string]*metricSeries, name string, labels map[string]string) *metricSeries {
+	key := labelString(labels)
+	if metrics[name] == nil {
+		metrics[name] = map[string]*metricSeries{}
+	}
+	if metrics[name][key] == nil {
+		metrics[name][key] = &metricSeries{labels: maps.Clone(labels)}
+	}
+	return metrics[name][key]
+}
+
+func (m *MemoryMetrics) Count(name string, labels map[string]string, delta float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.counters == nil {
+		m.counters = map[string]map[string]*metricSeries{}
+	}
+	series(m.counters, name, labels).value += delta
+}
+
+func (m *MemoryMetrics) Observe(name string, labels map[string]string, value float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.histograms == nil {
+		m.histograms = map[string]map[string]*metricSeries{}
+	}
+	s := series(m.histograms, name, labels)
+	bounds := m.bounds()
+	if s.buckets == nil {
+		s.buckets = make([]uint64, len(bounds))
+	}
+	s.value += value
+	s.count++
+	if i, _ := slices.BinarySearch(bounds, value); i < len(bounds) {
+		s.buckets[i]++
+	}
+}
+
+// Returns the value of the counter name with labels, or 0 if it was never counted.
+func (m *MemoryMetrics) Counter(name string, labels map[string]string) float64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if s := m.counters[name][labelString(labels)]; s != nil {
+		return s.value
+	}
+	return 0
+}
+
+// Returns the histogram name with labels. It is empty if nothing was observed.
+func (m *MemoryMetrics) Histogram(name string, labels map[string]string) Histogram {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	h := Histogram{Bounds: slices.Clone(m.bounds())}
+	h.Buckets = make([]uint64, len(h.Bounds))
+	if s := m.histograms[name][labelString(labels)]; s != nil {
+		h.Count, h.Sum = s.count, s.value
+		var total uint64
+		for i, n := range s.buckets {
+			total += n
+			h.Buckets[i] = total
+		}
+	}
+	return h
+}
+
+// Writes all metrics to w in the Prometheus text exposition format.
+func (m *MemoryMetrics) WritePrometheus(w io.Writer) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var b strings.Builder
+	for _, name := range slices.Sorted(maps.Keys(m.counters)) {
+		fmt.Fprintf(&b, "# TYPE %s counter\n", name)
+		for _, key := range slices.Sorted(maps.Keys(m.counters[name])) {
+			fmt.Fprintf(&b, "%s%s %s\n", name, key, formatFloat(m.counters[name][key].value))
+		}
+	}
+	bounds := m.bounds()
+	for _, name := range slices.Sorted(maps.Keys(m.histograms)) {
+		fmt.Fprintf(&b, "# TYPE %s histogram\n", name)
+		for _, key := range slices.Sorted(maps.Keys(m.histograms[name])) {
+			s := m.histograms[name][key]
+			var total uint64
+			for i, bound := range bounds {
+				total += s.buckets[i]
+				fmt.Fprintf(&b, "%s_bucket%s %d\n", name, withLabel(s.labels, "le", formatFloat(bound)), total)
+			}
+			fmt.Fprintf(&b, "%s_bucket%s %d\n", name, withLabel(s.labels, "le", "+Inf"), s.count)
+			fmt.Fprintf(&b, "%s_sum%s %s\n", name, key, formatFloat(s.value))
+			fmt.Fprintf(&b, "%s_count%s %d\n", name, key, s.count)
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// Serves all metrics in the Prometheus text exposition format, for use as a scrape endpoint.
+func (m *MemoryMetrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	m.WritePrometheus(w)
+}
+
+// Returns labels in the Prometheus format, e.g. `{method="GET",route="/v1/about"}`, sorted by name.
+func labelString(labels map[string]string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	var pairs []string
+	for _, name := range slices.Sorted(maps.Keys(labels)) {
+		pairs = append(pairs, fmt.Sprintf(`%s="%s"`, name, labelEscaper.Replace(labels[name])))
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func withLabel(labels map[string]string, name, value string) string {
+	labels = maps.Clone(labels)
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	labels[name] = value
+	return labelString(labels)
+}
+
+func formatFloat(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "+Inf"
+	case math.IsInf(f, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
@@ -0,0 +1,335 @@
+package signalmgr
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// How far a sent message got with one recipient. States only move forward.
+type DeliveryState int
+
+const (
+	// Sent to the server, no receipt yet.
+	DeliverySent DeliveryState = iota
+	// Delivered to one of the recipient's devices.
+	DeliveryDelivered
+	// Read by the recipient.
+	DeliveryRead
+	// Viewed by the recipient, for view-once media and stories.
+	DeliveryViewed
+)
+
+func (s DeliveryState) String() string {
+	switch s {
+	case DeliverySent:
+		return "sent"
+	case DeliveryDelivered:
+		return "delivered"
+	case DeliveryRead:
+		return "read"
+	case DeliveryViewed:
+		return "viewed"
+	}
+	return fmt.Sprintf("DeliveryState(%d)", int(s))
+}
+
+// Delivery status of a sent message with one recipient.
+type DeliveryStatus struct {
+	// Number of the account that sent the message.
+	Account string
+	// Recipient the message was sent to. For group messages this is the member the receipt came from.
+	Recipient string
+	// Group the message was sent in, in `group.` form, or empty for direct messages.
+	Group string
+	// Sent timestamp of the message.
+	Timestamp int64
+	State     DeliveryState
+	// When the message was sent and when it reached each state, zero for states not reached.
+	SentAt      time.Time
+	DeliveredAt time.Time
+	ReadAt      time.Time
+	ViewedAt    time.Time
+}
+
+// Reports whether the message reached state with the recipient.
+func (s DeliveryStatus) Reached(state DeliveryState) bool {
+	return s.State >= state
+}
+
+// A ReceiptTracker records sent messages per recipient and updates their delivery state from the receipts passed to Observe.
+//
+// Messages sent from linked devices are tracked from their sync messages. Group messages are tracked per member, from the first receipt of each member.
+type ReceiptTracker struct {
+	// Called after a recipient's state of a tracked message changes.
+	OnChange func(status DeliveryStatus)
+	// Forget messages sent longer than Retention ago. Zero keeps them until Forget is called.
+	Retention time.Duration
+
+	mu       sync.Mutex
+	messages map[storeKey]*trackedMessage
+	// Closed and replaced on every change, to wake up Wait.
+	changed chan struct{}
+}
+
+type trackedMessage struct {
+	recipients []string
+	statuses   []*DeliveryStatus
+}
+
+// Creates an empty ReceiptTracker.
+func NewReceiptTracker() *ReceiptTracker {
+	return &ReceiptTracker{}
+}
+
+func (t *ReceiptTracker) init() {
+	if t.messages == nil {
+		t.messages = map[storeKey]*trackedMessage{}
+		t.changed = make(chan struct{})
+	}
+}
+
+// Tracks the message account sent at timestamp to recipients, as passed to the send endpoints.
+func (t *ReceiptTracker) Track(account string, timestamp int64, recipients ...string) {
+	sentAt := time.UnixMilli(timestamp)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.init()
+	t.prune()
+	key := storeKey{account, timestamp}
+	message := t.messages[key]
+	if message == nil {
+		message = &trackedMessage{}
+		t.messages[key] = message
+	}
+	for _, recipient := range recipients {
+		if slices.Contains(message.recipients, recipient) {
+			continue
+		}
+		message.recipients = append(message.recipients, recipient)
+		if !strings.HasPrefix(recipient, "group.") {
+			message.statuses = append(message.statuses, &DeliveryStatus{
+				Account:   account,
+				Recipient: recipient,
+				Timestamp: timestamp,
+				SentAt:    sentAt,
+			})
+		}
+	}
+}
+
+// Tracks a sent message.
+func (t *ReceiptTracker) TrackSent(result SendResult) {
+	t.Track(result.Sender, result.Timestamp, result.Recipients...)
+}
+
+// Returns the status of the recipient with number or uuid, or nil.
+func (m *trackedMessage) status(number, uuid string) *DeliveryStatus {
+	for _, status := range m.statuses {
+		if status.Recipient != "" && (status.Recipient == number || status.Recipient == uuid) {
+			return status
+		}
+	}
+	return nil
+}
+
+// Returns the group the message was sent to, if any.
+func (m *trackedMessage) group() string {
+	for _, recipient := range m.recipients {
+		if strings.HasPrefix(recipient, "group.") {
+			return recipient
+		}
+	}
+	return ""
+}
+
+func (t *ReceiptTracker) prune() {
+	if t.Retention <= 0 {
+		return
+	}
+	cutoff := time.Now().Add(-t.Retention).UnixMilli()
+	maps.DeleteFunc(t.messages, func(k storeKey, _ *trackedMessage) bool { return k.timestamp < cutoff })
+}
+
+// Updates tracked messages from a receipt, and tracks messages sent from linked devices. Other messages are ignored.
+func (t *ReceiptTracker) Observe(m MessageResponse) {
+	switch m.Kind() {
+	case EventSyncSent:
+		sent := m.Envelope.SyncMessage.SentMessage
+		if sent.EditMessage.TargetSentTimestamp != 0 || sent.RemoteDelete.Timestamp != 0 || sent.Reaction.Emoji != "" {
+			return
+		}
+		recipient := sent.DestinationNumber
+		switch {
+		case sent.GroupInfo.GroupId != "":
+			recipient = groupRecipient(sent.GroupInfo.GroupId)
+		case recipient == "":
+			recipient = sent.DestinationUuid
+		}
+		if recipient != "" {
+			t.Track(m.Account, sent.Timestamp, recipient)
+		}
+	case EventReceipt:
+		t.receipt(m)
+	}
+}
+
+func (t *ReceiptTracker) receipt(m MessageResponse) {
+	e := m.Envelope
+	receipt := e.ReceiptMessage
+	state := DeliveryDelivered
+	switch {
+	case receipt.IsViewed:
+		state = DeliveryViewed
+	case receipt.IsRead:
+		state = DeliveryRead
+	}
+	at := time.UnixMilli(receipt.When)
+	if receipt.When == 0 {
+		at = time.UnixMilli(e.Timestamp)
+	}
+
+	var changed []DeliveryStatus
+	t.mu.Lock()
+	t.init()
+	for _, timestamp := range receipt.Timestamps {
+		message := t.messages[storeKey{m.Account, timestamp}]
+		if message == nil {
+			continue
+		}
+		status := message.status(e.SourceNumber, e.SourceUuid)
+		if status == nil {
+			group := message.group()
+			if group == "" {
+				continue
+			}
+			status = &DeliveryStatus{
+				Account:   m.Account,
+				Recipient: m.Sender(),
+				Group:     group,
+				Timestamp: timestamp,
+				SentAt:    time.UnixMilli(timestamp),
+			}
+			message.statuses = append(message.statuses, status)
+		}
+		if status.advance(state, at) {
+			changed = append(changed, *status)
+		}
+	}
+	if len(changed) > 0 {
+		close(t.changed)
+		t.changed = make(chan struct{})
+	}
+	t.mu.Unlock()
+
+	if t.OnChange != nil {
+		for _, status := range changed {
+			t.OnChange(status)
+		}
+	}
+}
+
+// Moves the status forward to state, filling in the times of states skipped over. Reports whether the state changed.
+func (s *DeliveryStatus) advance(state DeliveryState, at time.Time) bool {
+	if s.State >= state {
+		return false
+	}
+	s.State = state
+	for _, reached := range []struct {
+		state DeliveryState
+		at    *time.Time
+	}{
+		{DeliveryDelivered, &s.DeliveredAt},
+		{DeliveryRead, &s.ReadAt},
+		{DeliveryViewed, &s.ViewedAt},
+	} {
+		if reached.state <= state && reached.at.IsZero() {
+			*reached.at = at
+		}
+	}
+	return true
+}
+
+// Returns middleware that observes every message passing through a Router.
+func (t *ReceiptTracker) Middleware() Middleware {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, m MessageResponse) error {
+			t.Observe(m)
+			return next(ctx, m)
+		}
+	}
+}
+
+// Returns the statuses of the message account sent at timestamp, one per recipient, or nil if the message is not tracked.
+func (t *ReceiptTracker) Status(account string, timestamp int64) []DeliveryStatus {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	message := t.messages[storeKey{account, timestamp}]
+	if message == nil {
+		return nil
+	}
+	statuses := make([]DeliveryStatus, len(message.statuses))
+	for i, status := range message.statuses {
+		statuses[i] = *status
+	}
+	return statuses
+}
+
+// Returns the statuses of all tracked messages of account, or of all accounts if account is empty, that have not yet reached state with every recipient.
+func (t *ReceiptTracker) Pending(account string, state DeliveryState) (pending []DeliveryStatus) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for key, message := range t.messages {
+		if account != "" && key.account != account {
+			continue
+		}
+		for _, status := range message.statuses {
+			if !status.Reached(state) {
+				pending = append(pending, *status)
+			}
+		}
+	}
+	return
+}
+
+// Waits until the message account sent at timestamp reaches state with recipient, a number or UUID, and returns its status.
+//
+// Use a context with a deadline to wait with a timeout, the context's error is returned when it is done. Returns an error matching ErrNotFound if the message is not tracked.
+func (t *ReceiptTracker) Wait(ctx context.Context, account string, timestamp int64, recipient string, state DeliveryState) (DeliveryStatus, error) {
+	for {
+		t.mu.Lock()
+		t.init()
+		message := t.messages[storeKey{account, timestamp}]
+		if message == nil {
+			t.mu.Unlock()
+			return DeliveryStatus{}, fmt.Errorf("message %d of %s is not tracked: %w", timestamp, account, ErrNotFound)
+		}
+		status := message.status(recipient, recipient)
+		if status != nil && status.Reached(state) {
+			t.mu.Unlock()
+			return *status, nil
+		}
+		changed := t.changed
+		t.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			if status != nil {
+				return *status, ctx.Err()
+			}
+			return DeliveryStatus{}, ctx.Err()
+		case <-changed:
+		}
+	}
+}
+
+// Stops tracking the message account sent at timestamp.
+func (t *ReceiptTracker) Forget(account string, timestamp int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.messages, storeKey{account, timestamp})
+}
@@ -0,0 +1,77 @@
+package signalmgr_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/DonovanDiamond/signalmgr"
+	"github.com/DonovanDiamond/signalmgr/signalmgrtest"
+)
+
+func TestReceiptTrackerWait(t *testing.T) {
+	const thirdNumber = "+14155550177"
+	tracker := signalmgr.NewReceiptTracker()
+	var (
+		mu      sync.Mutex
+		changes []signalmgr.DeliveryStatus
+	)
+	tracker.OnChange = func(status signalmgr.DeliveryStatus) {
+		mu.Lock()
+		defer mu.Unlock()
+		changes = append(changes, status)
+	}
+	tracker.TrackSent(signalmgr.SendResult{Sender: testNumber, Timestamp: 1000, Recipients: []string{otherNumber, thirdNumber}})
+
+	receipt := func(sender, kind string, at int64) {
+		e := signalmgrtest.ReceiptEnvelope(sender, kind, 1000)
+		e.Timestamp = at
+		tracker.Observe(signalmgr.MessageResponse{Envelope: e, Account: testNumber})
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		time.Sleep(10 * time.Millisecond)
+		receipt(otherNumber, "delivery", 2000)
+		receipt(otherNumber, "read", 3000)
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	status, err := tracker.Wait(ctx, testNumber, 1000, otherNumber, signalmgr.DeliveryRead)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.State != signalmgr.DeliveryRead || status.DeliveredAt.UnixMilli() != 2000 || status.ReadAt.UnixMilli() != 3000 {
+		t.Errorf("got status %+v", status)
+	}
+	<-done
+
+	// A read receipt without a delivery receipt fills in the delivery time.
+	receipt(thirdNumber, "read", 4000)
+	status, err = tracker.Wait(ctx, testNumber, 1000, thirdNumber, signalmgr.DeliveryDelivered)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.State != signalmgr.DeliveryRead || status.DeliveredAt.UnixMilli() != 4000 {
+		t.Errorf("got status %+v", status)
+	}
+	// Receipts that do not advance the state are not changes.
+	receipt(thirdNumber, "delivery", 5000)
+	mu.Lock()
+	if len(changes) != 3 {
+		t.Errorf("got %d changes, want 3: %+v", len(changes), changes)
+	}
+	mu.Unlock()
+
+	short, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if _, err := tracker.Wait(short, testNumber, 1000, otherNumber, signalmgr.DeliveryViewed); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("unreached state: got %v, want DeadlineExceeded", err)
+	}
+	if _, err := tracker.Wait(ctx, testNumber, 9999, otherNumber, signalmgr.DeliveryRead); !errors.Is(err, signalmgr.ErrNotFound) {
+		t.Errorf("untracked message: got %v, want ErrNotFound", err)
+	}
+}
@@ -0,0 +1,98 @@
+package signalmgr
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Options for Receive. The zero value uses sensible defaults.
+type ReceiveOptions struct {
+	// Used for the socket in `json-rpc` mode. In `normal` and `native` mode OnStateChange and Reconnect apply to polling:
+	// a failed poll reports ConnReconnecting and is retried with backoff, the next successful poll reports ConnConnected.
+	SubscribeOptions
+	// Interval between polls in `normal` and `native` mode. Defaults to 2 seconds.
+	PollInterval time.Duration
+	// Mode the signal api is running in. If empty, it is looked up with GetAbout.
+	Mode string
+}
+
+// Receives Signal Messages and sends them to the `messages` channel, regardless of the mode the signal api is running in.
+//
+// In `json-rpc` mode this uses Subscribe, in `normal` and `native` mode it polls GetMessages every PollInterval.
+//
+// Will only return once ctx is done, returning ctx.Err(), once opts.Reconnect.MaxAttempts consecutive attempts have failed, or if the mode cannot be determined.
+func (a *Account) Receive(ctx context.Context, messages chan<- MessageResponse, opts *ReceiveOptions) error {
+	if opts == nil {
+		opts = &ReceiveOptions{}
+	}
+	mode := opts.Mode
+	if mode == "" {
+		about, err := a.client().GetAboutCtx(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to get api mode: %w", err)
+		}
+		mode = about.Mode
+	}
+	switch mode {
+	case "json-rpc":
+		return a.Subscribe(ctx, messages, &opts.SubscribeOptions)
+	case "normal", "native":
+		return a.poll(ctx, messages, opts)
+	}
+	return fmt.Errorf("unsupported api mode %q", mode)
+}
+
+// Polls GetMessages and sends the results to the `messages` channel until ctx is done or too many polls fail.
+func (a *Account) poll(ctx context.Context, messages chan<- MessageResponse, opts *ReceiveOptions) error {
+	reconnect := opts.reconnectPolicy()
+	interval := opts.PollInterval
+	if interval <= 0 {
+		interval = 2 * time.Second
+	}
+
+	failures := 0
+	connected := false
+	for {
+		list, err := a.GetMessagesCtx(ctx)
+		if ctx.Err() != nil {
+			opts.setState(ConnClosed, nil)
+			return ctx.Err()
+		}
+
+		wait := interval
+		if err != nil {
+			failures++
+			if reconnect.MaxAttempts > 0 && failures >= reconnect.MaxAttempts {
+				opts.setState(ConnClosed, err)
+				return err
+			}
+			connected = false
+			opts.setState(ConnReconnecting, err)
+			wait = reconnect.backoff(failures)
+		} else {
+			failures = 0
+			if !connected {
+				connected = true
+				opts.setState(ConnConnected, nil)
+			}
+			for _, m := range list {
+				select {
+				case messages <- m:
+				case <-ctx.Done():
+					opts.setState(ConnClosed, nil)
+					return ctx.Err()
+				}
+			}
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			opts.setState(ConnClosed, nil)
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
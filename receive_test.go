@@ -0,0 +1,90 @@
+package signalmgr_test
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DonovanDiamond/signalmgr"
+	"github.com/DonovanDiamond/signalmgr/signalmgrtest"
+)
+
+func receive(t *testing.T, account *signalmgr.Account, opts *signalmgr.ReceiveOptions) *subscription {
+	t.Helper()
+	return startSubscription(t, &opts.SubscribeOptions, func(ctx context.Context, messages chan<- signalmgr.MessageResponse) error {
+		return account.Receive(ctx, messages, opts)
+	})
+}
+
+func TestReceiveModes(t *testing.T) {
+	for _, mode := range []string{"json-rpc", "normal", "native"} {
+		srv := signalmgrtest.NewServer(testNumber)
+		srv.SetMode(mode)
+		s := receive(t, srv.Account(testNumber), &signalmgr.ReceiveOptions{PollInterval: 10 * time.Millisecond})
+
+		s.waitState(t, signalmgr.ConnConnected)
+		srv.Deliver(testNumber, signalmgrtest.TextEnvelope(otherNumber, "hi"))
+		if m := s.receive(t); m.Envelope.DataMessage.Message != "hi" || m.Account != testNumber {
+			t.Errorf("%s: got %+v", mode, m)
+		}
+		if n := len(srv.RequestsTo(http.MethodGet, "/v1/about")); n != 1 {
+			t.Errorf("%s: looked up the mode %d times, want once", mode, n)
+		}
+		polls := len(srv.RequestsTo(http.MethodGet, "/v1/receive/"+testNumber))
+		if mode == "json-rpc" && polls != 1 || mode != "json-rpc" && polls < 2 {
+			t.Errorf("%s: got %d requests to receive", mode, polls)
+		}
+		if got := srv.Connections(testNumber); (got == 1) != (mode == "json-rpc") {
+			t.Errorf("%s: got %d websockets", mode, got)
+		}
+		srv.Close()
+	}
+}
+
+func TestReceivePollFailures(t *testing.T) {
+	srv := signalmgrtest.NewServer(testNumber)
+	defer srv.Close()
+	client := srv.Client()
+	// Failed polls are retried by Receive, not by the client.
+	client.Retry = &signalmgr.RetryPolicy{}
+	account := &signalmgr.Account{Number: testNumber, Client: client}
+	s := receive(t, account, &signalmgr.ReceiveOptions{Mode: "normal", PollInterval: 10 * time.Millisecond})
+
+	s.waitState(t, signalmgr.ConnConnected)
+	srv.FailNext(http.MethodGet, "/v1/receive/"+testNumber, http.StatusInternalServerError, "signal-cli restarting")
+	s.waitState(t, signalmgr.ConnReconnecting)
+	s.waitState(t, signalmgr.ConnConnected)
+	// The mode was given, so it is not looked up.
+	if n := len(srv.RequestsTo(http.MethodGet, "/v1/about")); n != 0 {
+		t.Errorf("looked up the mode %d times", n)
+	}
+
+	// Polling gives up after MaxAttempts consecutive failures.
+	down := signalmgrtest.NewServer(testNumber)
+	defer down.Close()
+	client = down.Client()
+	client.Retry = &signalmgr.RetryPolicy{}
+	reconnect := fastRetry()
+	reconnect.MaxAttempts = 2
+	for range reconnect.MaxAttempts {
+		down.FailNext(http.MethodGet, "/v1/receive/"+testNumber, http.StatusInternalServerError, "down")
+	}
+	opts := &signalmgr.ReceiveOptions{Mode: "native", PollInterval: time.Millisecond}
+	opts.Reconnect = reconnect
+	err := (&signalmgr.Account{Number: testNumber, Client: client}).Receive(context.Background(), nil, opts)
+	if err == nil || !strings.Contains(err.Error(), "down") {
+		t.Errorf("got %v, want the poll error", err)
+	}
+}
+
+func TestReceiveUnsupportedMode(t *testing.T) {
+	srv := signalmgrtest.NewServer(testNumber)
+	defer srv.Close()
+	srv.SetMode("daemon")
+	err := srv.Account(testNumber).Receive(context.Background(), nil, nil)
+	if err == nil || !strings.Contains(err.Error(), `unsupported api mode "daemon"`) {
+		t.Errorf("got %v", err)
+	}
+}
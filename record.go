@@ -0,0 +1,486 @@
+package signalmgr
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gorilla/websocket"
+)
+
+// A Redactor removes phone numbers and secrets from recorded traffic.
+//
+// Numbers are replaced with stable placeholders in the +1555 range, so a recording replays for the same numbers it was recorded with.
+type Redactor struct {
+	// Headers whose values are replaced with "REDACTED". If nil, DefaultRedactor's are used.
+	Headers []string
+	// JSON fields whose string values are replaced with "REDACTED". If nil, DefaultRedactor's are used. Must not change once the Redactor is used.
+	Fields []string
+	// Keep phone numbers as they are.
+	KeepNumbers bool
+
+	fieldsOnce   sync.Once
+	fieldPattern *regexp.Regexp
+}
+
+// DefaultRedactor is used by a Recorder or Replay without a Redactor.
+var DefaultRedactor = &Redactor{
+	Headers: []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie", "X-Api-Key", "X-Signature"},
+	Fields:  []string{"token", "captcha", "pin", "password", "pack_key"},
+}
+
+const redacted = "REDACTED"
+
+// Matches phone numbers, also with the "+" URL-encoded as in query strings.
+var numberPattern = regexp.MustCompile(`(?:\+|%2[Bb])[1-9][0-9]{6,14}`)
+
+// Returns a placeholder for number that is the same on every run, keeping a URL-encoded "+" encoded. Numbers already in the placeholder range are kept.
+func redactNumber(number string) string {
+	prefix := "+"
+	if !strings.HasPrefix(number, prefix) {
+		prefix, number = number[:3], "+"+number[3:]
+	}
+	if strings.HasPrefix(number, "+1555") {
+		return prefix + number[1:]
+	}
+	h := fnv.New32a()
+	h.Write([]byte(number))
+	return fmt.Sprintf("%s1555%07d", prefix, h.Sum32()%10_000_000)
+}
+
+func (r *Redactor) text(s string) string {
+	if r.KeepNumbers {
+		return s
+	}
+	return numberPattern.ReplaceAllStringFunc(s, redactNumber)
+}
+
+func (r *Redactor) fields() []string {
+	if r.Fields == nil {
+		return DefaultRedactor.Fields
+	}
+	return r.Fields
+}
+
+func (r *Redactor) headers() []string {
+	if r.Headers == nil {
+		return DefaultRedactor.Headers
+	}
+	return r.Headers
+}
+
+func (r *Redactor) body(body []byte) []byte {
+	if len(body) == 0 || !utf8.Valid(body) {
+		return body
+	}
+	s := r.text(string(body))
+	if pattern := r.fieldsPattern(); pattern != nil {
+		s = pattern.ReplaceAllString(s, `${1}"`+redacted+`"`)
+	}
+	return []byte(s)
+}
+
+// Returns the pattern matching the string values of the redacted fields, compiled on first use. Returns nil if no fields are redacted.
+func (r *Redactor) fieldsPattern() *regexp.Regexp {
+	r.fieldsOnce.Do(func() {
+		fields := r.fields()
+		if len(fields) == 0 {
+			return
+		}
+		quoted := make([]string, len(fields))
+		for i, field := range fields {
+			quoted[i] = regexp.QuoteMeta(field)
+		}
+		r.fieldPattern = regexp.MustCompile(`("(?:` + strings.Join(quoted, "|") + `)"\s*:\s*)"(?:[^"\\]|\\.)*"`)
+	})
+	return r.fieldPattern
+}
+
+func (r *Redactor) header(header http.Header) http.Header {
+	if len(header) == 0 {
+		return nil
+	}
+	header = header.Clone()
+	for _, key := range r.headers() {
+		if header.Get(key) != "" {
+			header.Set(key, redacted)
+		}
+	}
+	return header
+}
+
+// One line of a recording.
+type recordEntry struct {
+	// "http" for a request, "dial" for a websocket dial and "frame" for a message read from a websocket.
+	Kind string `json:"kind"`
+	// Websocket the dial or frame belongs to.
+	Conn           int         `json:"conn,omitempty"`
+	Method         string      `json:"method,omitempty"`
+	Path           string      `json:"path,omitempty"`
+	RequestHeader  http.Header `json:"request_header,omitempty"`
+	RequestBody    string      `json:"request_body,omitempty"`
+	Status         int         `json:"status,omitempty"`
+	ResponseHeader http.Header `json:"response_header,omitempty"`
+	// Response body, or the message of a frame.
+	ResponseBody string `json:"response_body,omitempty"`
+	// Whether ResponseBody is base64 encoded, for binary bodies such as attachments.
+	Base64 bool   `json:"base64,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+func (e *recordEntry) setResponseBody(body []byte) {
+	if utf8.Valid(body) {
+		e.ResponseBody = string(body)
+		return
+	}
+	e.ResponseBody = base64.StdEncoding.EncodeToString(body)
+	e.Base64 = true
+}
+
+func (e *recordEntry) responseBody() []byte {
+	if e.Base64 {
+		body, _ := base64.StdEncoding.DecodeString(e.ResponseBody)
+		return body
+	}
+	return []byte(e.ResponseBody)
+}
+
+// Transports that wrap the client's default transport when they have none of their own.
+type transportWrapper interface {
+	wrap(next Transport) Transport
+}
+
+// A Recorder is a Transport that writes every request and response, and every message read from a websocket, to a JSON lines file for Replay.
+//
+// Recorded traffic is redacted with its Redactor. Create it with NewRecorder, a Recorder without a writer fails every request.
+type Recorder struct {
+	// Transport requests are sent through. If nil, the client's default transport is used.
+	Transport Transport
+	// If nil, DefaultRedactor is used.
+	Redactor *Redactor
+
+	mu    sync.Mutex
+	w     io.Writer
+	conns int
+	err   error
+}
+
+// Creates a Recorder writing to w.
+func NewRecorder(w io.Writer) *Recorder {
+	return &Recorder{w: w}
+}
+
+// Returns the first error writing the recording, if any.
+func (r *Recorder) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.err
+}
+
+func (r *Recorder) redactor() *Redactor {
+	if r.Redactor == nil {
+		return DefaultRedactor
+	}
+	return r.Redactor
+}
+
+func (r *Recorder) write(entry recordEntry) {
+	line, err := json.Marshal(entry)
+	if err == nil {
+		_, err = r.w.Write(append(line, '\n'))
+	}
+	if err != nil && r.err == nil {
+		r.err = err
+	}
+}
+
+func (r *Recorder) wrap(next Transport) Transport {
+	if r.Transport != nil {
+		return r
+	}
+	return recorderWith{r, next}
+}
+
+func (r *Recorder) RoundTrip(ctx context.Context, req *Request) (*Response, error) {
+	return r.roundTrip(ctx, r.Transport, req)
+}
+
+func (r *Recorder) Dial(ctx context.Context, req *Request) (Conn, error) {
+	return r.dial(ctx, r.Transport, req)
+}
+
+// Returned by a Recorder that was not created with NewRecorder.
+var errNoRecording = errors.New("recorder has no writer, create it with NewRecorder")
+
+func (r *Recorder) roundTrip(ctx context.Context, next Transport, req *Request) (*Response, error) {
+	if r.w == nil {
+		return nil, errNoRecording
+	}
+	redactor := r.redactor()
+	entry := recordEntry{
+		Kind:          "http",
+		Method:        req.Method,
+		Path:          redactor.text(req.Path),
+		RequestHeader: redactor.header(req.Header),
+		RequestBody:   string(redactor.body(req.Body)),
+	}
+	resp, err := next.RoundTrip(ctx, req)
+	if err != nil {
+		if ctx.Err() == nil {
+			entry.Error = err.Error()
+			r.mu.Lock()
+			r.write(entry)
+			r.mu.Unlock()
+		}
+		return nil, err
+	}
+	// The body is read in full to record it, streamed responses included.
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	entry.Status = resp.StatusCode
+	entry.ResponseHeader = redactor.header(resp.Header)
+	entry.setResponseBody(redactor.body(body))
+	r.mu.Lock()
+	r.write(entry)
+	r.mu.Unlock()
+	return resp, nil
+}
+
+func (r *Recorder) dial(ctx context.Context, next Transport, req *Request) (Conn, error) {
+	if r.w == nil {
+		return nil, errNoRecording
+	}
+	redactor := r.redactor()
+	conn, err := next.Dial(ctx, req)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.conns++
+	entry := recordEntry{
+		Kind:          "dial",
+		Conn:          r.conns,
+		Path:          redactor.text(req.Path),
+		RequestHeader: redactor.header(req.Header),
+	}
+	if err != nil {
+		if ctx.Err() == nil {
+			entry.Error = err.Error()
+			r.write(entry)
+		}
+		return nil, err
+	}
+	r.write(entry)
+	return &recordingConn{Conn: conn, r: r, id: r.conns, path: entry.Path}, nil
+}
+
+// A Recorder using the client's default transport.
+type recorderWith struct {
+	r    *Recorder
+	next Transport
+}
+
+func (t recorderWith) RoundTrip(ctx context.Context, req *Request) (*Response, error) {
+	return t.r.roundTrip(ctx, t.next, req)
+}
+
+func (t recorderWith) Dial(ctx context.Context, req *Request) (Conn, error) {
+	return t.r.dial(ctx, t.next, req)
+}
+
+// A websocket whose messages are recorded as they are read.
+type recordingConn struct {
+	Conn
+	r    *Recorder
+	id   int
+	path string
+}
+
+func (c *recordingConn) ReadMessage() (int, []byte, error) {
+	messageType, data, err := c.Conn.ReadMessage()
+	if err == nil {
+		entry := recordEntry{Kind: "frame", Conn: c.id, Path: c.path}
+		entry.setResponseBody(c.r.redactor().body(data))
+		c.r.mu.Lock()
+		c.r.write(entry)
+		c.r.mu.Unlock()
+	}
+	return messageType, data, err
+}
+
+// A Replay is a Transport that serves the traffic recorded by a Recorder.
+//
+// Requests are matched by method and redacted path, and each recorded response is served once, in recorded order. Recorded websockets return their messages in order, then stay open until closed.
+type Replay struct {
+	// Redactor applied to requests before matching them. It must match the recording's. If nil, DefaultRedactor is used.
+	Redactor *Redactor
+
+	mu      sync.Mutex
+	entries []recordEntry
+	used    []bool
+}
+
+// Loads a recording from r.
+func NewReplay(r io.Reader) (*Replay, error) {
+	replay := &Replay{}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 256<<20)
+	for line := 1; scanner.Scan(); line++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var entry recordEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("failed to read recording line %d: %w", line, err)
+		}
+		replay.entries = append(replay.entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read recording: %w", err)
+	}
+	replay.used = make([]bool, len(replay.entries))
+	return replay, nil
+}
+
+// Loads the recording at path.
+func OpenReplay(path string) (*Replay, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return NewReplay(f)
+}
+
+func (r *Replay) redactor() *Redactor {
+	if r.Redactor == nil {
+		return DefaultRedactor
+	}
+	return r.Redactor
+}
+
+// Returns the first unused entry of kind for method and path, marking it used.
+func (r *Replay) next(kind, method, path string) (recordEntry, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, entry := range r.entries {
+		if !r.used[i] && entry.Kind == kind && entry.Method == method && entry.Path == path {
+			r.used[i] = true
+			return entry, true
+		}
+	}
+	return recordEntry{}, false
+}
+
+func (r *Replay) RoundTrip(ctx context.Context, req *Request) (*Response, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	path := r.redactor().text(req.Path)
+	entry, ok := r.next("http", req.Method, path)
+	if !ok {
+		return nil, fmt.Errorf("no recorded response for %s %s", req.Method, path)
+	}
+	if entry.Error != "" {
+		return nil, errors.New(entry.Error)
+	}
+	header := entry.ResponseHeader
+	if header == nil {
+		header = http.Header{}
+	}
+	return &Response{
+		StatusCode: entry.Status,
+		Header:     header,
+		Body:       io.NopCloser(bytes.NewReader(entry.responseBody())),
+	}, nil
+}
+
+func (r *Replay) Dial(ctx context.Context, req *Request) (Conn, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	path := r.redactor().text(req.Path)
+	entry, ok := r.next("dial", "", path)
+	if !ok {
+		return nil, fmt.Errorf("no recorded websocket for %s", path)
+	}
+	if entry.Error != "" {
+		return nil, errors.New(entry.Error)
+	}
+	conn := &replayConn{closed: make(chan struct{})}
+	r.mu.Lock()
+	for i, frame := range r.entries {
+		if !r.used[i] && frame.Kind == "frame" && frame.Conn == entry.Conn {
+			r.used[i] = true
+			conn.frames = append(conn.frames, frame.responseBody())
+		}
+	}
+	r.mu.Unlock()
+	return conn, nil
+}
+
+// A replayed websocket.
+type replayConn struct {
+	mu     sync.Mutex
+	frames [][]byte
+	pong   func(string) error
+	closed chan struct{}
+	once   sync.Once
+}
+
+func (c *replayConn) ReadMessage() (int, []byte, error) {
+	c.mu.Lock()
+	if len(c.frames) > 0 {
+		frame := c.frames[0]
+		c.frames = c.frames[1:]
+		c.mu.Unlock()
+		return websocket.TextMessage, frame, nil
+	}
+	c.mu.Unlock()
+	<-c.closed
+	return 0, nil, net.ErrClosed
+}
+
+// Answers pings right away, as the recorded server did.
+func (c *replayConn) WriteControl(messageType int, data []byte, deadline time.Time) error {
+	c.mu.Lock()
+	pong := c.pong
+	c.mu.Unlock()
+	if messageType == websocket.PingMessage && pong != nil {
+		return pong(string(data))
+	}
+	return nil
+}
+
+// Replayed websockets never time out.
+func (c *replayConn) SetReadDeadline(t time.Time) error {
+	return nil
+}
+
+func (c *replayConn) SetPongHandler(h func(appData string) error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.pong = h
+}
+
+func (c *replayConn) Close() error {
+	c.once.Do(func() { close(c.closed) })
+	return nil
+}
@@ -0,0 +1,121 @@
+package signalmgr_test
+
+import (
+	"bytes"
+	"context"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DonovanDiamond/signalmgr"
+	"github.com/DonovanDiamond/signalmgr/signalmgrtest"
+)
+
+// Makes the same requests against client, returning what a test would assert on.
+func recordedSession(t *testing.T, ctx context.Context, client *signalmgr.Client, deliver func()) (timestamp int64, received string, sender string) {
+	t.Helper()
+	account := &signalmgr.Account{Number: testNumber, Client: client}
+	if err := account.PostRegisterCtx(ctx, "signalcaptcha://secret-captcha", false); err != nil {
+		t.Fatal(err)
+	}
+	if err := account.PostRegisterVerifyCtx(ctx, "123-456", "9876"); err != nil {
+		t.Fatal(err)
+	}
+	// The number is URL-encoded in the query.
+	if results, err := client.GetSearchCtx(ctx, []string{otherNumber}); err != nil || len(results) != 1 {
+		t.Fatalf("got %v, %v", results, err)
+	}
+	result, err := account.NewMessage(otherNumber).Text("call " + otherNumber).Send(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	socketCtx, stop := context.WithCancel(ctx)
+	defer stop()
+	messages := make(chan signalmgr.MessageResponse)
+	go account.GetMessagesSocketCtx(socketCtx, messages)
+	deliver()
+	select {
+	case m := <-messages:
+		return result.Timestamp, m.Envelope.DataMessage.Message, m.Sender()
+	case <-ctx.Done():
+		t.Fatal("no message received")
+		return
+	}
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	srv := signalmgrtest.NewServer(testNumber)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var recording bytes.Buffer
+	recorder := signalmgr.NewRecorder(&recording)
+	client := srv.Client()
+	client.Transport = recorder
+	client.Headers = map[string]string{"Authorization": "Basic c2VjcmV0"}
+	timestamp, text, sender := recordedSession(t, ctx, client, func() {
+		for srv.Connections(testNumber) == 0 {
+			time.Sleep(5 * time.Millisecond)
+		}
+		srv.Deliver(testNumber, signalmgrtest.TextEnvelope(otherNumber, "hi"))
+	})
+	srv.Close()
+	if err := recorder.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if text != "hi" || sender != otherNumber {
+		t.Fatalf("received %q from %s", text, sender)
+	}
+
+	log := recording.String()
+	for _, secret := range []string{testNumber, otherNumber, url.QueryEscape(otherNumber), "secret-captcha", "9876", "c2VjcmV0"} {
+		if strings.Contains(log, secret) {
+			t.Errorf("recording contains %q:\n%s", secret, log)
+		}
+	}
+	if !strings.Contains(log, "REDACTED") || !strings.Contains(log, "+1555") {
+		t.Errorf("recording is not redacted:\n%s", log)
+	}
+
+	replay, err := signalmgr.NewReplay(&recording)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The server is gone, everything is served from the recording.
+	client = &signalmgr.Client{URL: srv.URL, Transport: replay}
+	gotTimestamp, gotText, gotSender := recordedSession(t, ctx, client, func() {})
+	if gotTimestamp != timestamp || gotText != "hi" {
+		t.Errorf("replayed timestamp %d and %q, want %d and %q", gotTimestamp, gotText, timestamp, "hi")
+	}
+	// Numbers in responses stay redacted.
+	if !strings.HasPrefix(gotSender, "+1555") {
+		t.Errorf("replayed sender %s", gotSender)
+	}
+
+	// Each response is served once.
+	account := &signalmgr.Account{Number: testNumber, Client: client}
+	if _, err := account.NewMessage(otherNumber).Text("again").Send(ctx); err == nil {
+		t.Error("replayed a response twice")
+	}
+}
+
+func TestRecorderWithoutWriter(t *testing.T) {
+	srv := signalmgrtest.NewServer(testNumber)
+	defer srv.Close()
+	client := srv.Client()
+	client.Transport = &signalmgr.Recorder{}
+	client.Retry = &signalmgr.RetryPolicy{}
+	ctx := context.Background()
+
+	if _, err := client.GetAboutCtx(ctx); err == nil || !strings.Contains(err.Error(), "NewRecorder") {
+		t.Errorf("request: got %v, want an error", err)
+	}
+	if err := client.Account(testNumber).GetMessagesSocketCtx(ctx, nil); err == nil || !strings.Contains(err.Error(), "NewRecorder") {
+		t.Errorf("websocket: got %v, want an error", err)
+	}
+	if n := len(srv.Requests()); n != 0 {
+		t.Errorf("sent %d requests", n)
+	}
+}
@@ -0,0 +1,125 @@
+package signalmgr
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand/v2"
+	"net/http"
+	"slices"
+	"time"
+)
+
+// A RetryPolicy decides if and when a failed request is sent again.
+//
+// Only GET requests are retried unless the policy lists other Methods or the request's context was created with WithRetry.
+type RetryPolicy struct {
+	// Maximum number of attempts, including the first. Values below 2 disable retries.
+	MaxAttempts int
+	// Delay before the first retry.
+	InitialBackoff time.Duration
+	// Upper bound for the delay between attempts. Zero means no bound.
+	MaxBackoff time.Duration
+	// Factor the delay grows by after each attempt. Values below 1 are treated as 1.
+	Multiplier float64
+	// Fraction of each delay that is randomised, between 0 and 1. 0.2 spreads a 1s delay over 0.8s to 1.2s.
+	Jitter float64
+	// HTTP status codes of API errors that are retried.
+	RetryableStatusCodes []int
+	// Methods that are retried, in addition to GET.
+	Methods []string
+	// Reports whether err should be retried. If nil, connection errors and API errors with a status in RetryableStatusCodes are retried.
+	Retryable func(err error) bool
+}
+
+// DefaultRetryPolicy is used by clients without a Retry policy. It retries GET requests up to 4 times over roughly 3 seconds, which covers signal-cli restarting inside the container.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:    4,
+	InitialBackoff: 250 * time.Millisecond,
+	MaxBackoff:     2 * time.Second,
+	Multiplier:     2,
+	Jitter:         0.2,
+	RetryableStatusCodes: []int{
+		http.StatusInternalServerError,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout,
+	},
+}
+
+type retryKey struct{}
+
+// Returns a copy of ctx that allows requests made with it to be retried regardless of their method.
+//
+// Use this to opt in to retrying non-idempotent requests like PostSendCtx, which may result in a message being sent twice.
+func WithRetry(ctx context.Context) context.Context {
+	return context.WithValue(ctx, retryKey{}, true)
+}
+
+func (c *Client) retryPolicy() *RetryPolicy {
+	if c.Retry == nil {
+		return &DefaultRetryPolicy
+	}
+	return c.Retry
+}
+
+// Calls attempt until it succeeds or the client's RetryPolicy gives up on retrying method.
+func (c *Client) withRetry(ctx context.Context, method string, attempt func() error) (err error) {
+	policy := c.retryPolicy()
+	attempts := 1
+	if policy.allows(ctx, method) {
+		attempts = max(policy.MaxAttempts, 1)
+	}
+	for i := 1; ; i++ {
+		err = attempt()
+		if err == nil || i >= attempts || ctx.Err() != nil || !policy.retryable(err) {
+			return
+		}
+		timer := time.NewTimer(policy.backoff(i))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+func (p *RetryPolicy) allows(ctx context.Context, method string) bool {
+	if method == http.MethodGet || slices.Contains(p.Methods, method) {
+		return true
+	}
+	optIn, _ := ctx.Value(retryKey{}).(bool)
+	return optIn
+}
+
+func (p *RetryPolicy) retryable(err error) bool {
+	if p.Retryable != nil {
+		return p.Retryable(err)
+	}
+	var timeout *timeoutError
+	if errors.As(err, &timeout) {
+		// Only the client's Timeout passed, a done ctx stops retrying before this.
+		return true
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return slices.Contains(p.RetryableStatusCodes, apiErr.StatusCode)
+	}
+	return true
+}
+
+// Returns the delay before the attempt following attempt number attempt.
+func (p *RetryPolicy) backoff(attempt int) time.Duration {
+	delay := float64(p.InitialBackoff) * math.Pow(max(p.Multiplier, 1), float64(attempt-1))
+	if p.MaxBackoff > 0 {
+		delay = min(delay, float64(p.MaxBackoff))
+	}
+	if jitter := min(max(p.Jitter, 0), 1); jitter > 0 {
+		delay += delay * jitter * (2*rand.Float64() - 1)
+	}
+	return time.Duration(delay)
+}
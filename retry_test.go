@@ -0,0 +1,112 @@
+package signalmgr_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/DonovanDiamond/signalmgr"
+	"github.com/DonovanDiamond/signalmgr/signalmgrtest"
+)
+
+// Returns the default policy without delays between attempts.
+func fastRetry() *signalmgr.RetryPolicy {
+	policy := signalmgr.DefaultRetryPolicy
+	policy.InitialBackoff = time.Millisecond
+	policy.Jitter = 0
+	return &policy
+}
+
+func TestRetry(t *testing.T) {
+	srv := signalmgrtest.NewServer(testNumber)
+	defer srv.Close()
+	client := srv.Client()
+	client.Retry = fastRetry()
+	account := &signalmgr.Account{Number: testNumber, Client: client}
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		statuses []int
+		// Whether the request succeeds.
+		ok       bool
+		attempts int
+	}{
+		{"retried until it succeeds", []int{503, 502}, true, 3},
+		{"gives up after MaxAttempts", []int{500, 500, 500, 500}, false, 4},
+		{"client errors are not retried", []int{400}, false, 1},
+	}
+	for _, tt := range tests {
+		srv.Reset()
+		for _, status := range tt.statuses {
+			srv.FailNext(http.MethodGet, "/v1/groups/"+testNumber, status, "failed")
+		}
+		_, err := account.GetGroupsCtx(ctx)
+		if (err == nil) != tt.ok {
+			t.Errorf("%s: got error %v", tt.name, err)
+		}
+		if got := len(srv.RequestsTo(http.MethodGet, "/v1/groups/"+testNumber)); got != tt.attempts {
+			t.Errorf("%s: got %d attempts, want %d", tt.name, got, tt.attempts)
+		}
+	}
+
+	srv.Reset()
+	srv.FailNext(http.MethodPost, "/v2/send", http.StatusServiceUnavailable, "failed")
+	if _, err := account.NewMessage(otherNumber).Text("hi").Send(ctx); err == nil {
+		t.Error("POST was retried")
+	}
+	srv.FailNext(http.MethodPost, "/v2/send", http.StatusServiceUnavailable, "failed")
+	if _, err := account.NewMessage(otherNumber).Text("hi").Send(signalmgr.WithRetry(ctx)); err != nil {
+		t.Errorf("POST with WithRetry: %v", err)
+	}
+	if got := len(srv.RequestsTo(http.MethodPost, "/v2/send")); got != 3 {
+		t.Errorf("got %d sends, want 3", got)
+	}
+}
+
+func TestClientTimeout(t *testing.T) {
+	var attempts atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts.Add(1)
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name    string
+		timeout time.Duration
+		// Deadline of the request's context, zero for none.
+		deadline time.Duration
+		attempts int32
+	}{
+		// Only the client's Timeout passed, so the request is retried.
+		{"client timeout", 20 * time.Millisecond, 0, 2},
+		{"done ctx", time.Second, 20 * time.Millisecond, 1},
+	}
+	for _, tt := range tests {
+		attempts.Store(0)
+		client := &signalmgr.Client{URL: srv.URL, Timeout: tt.timeout, Retry: fastRetry()}
+		client.Retry.MaxAttempts = 2
+		ctx := context.Background()
+		if tt.deadline > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, tt.deadline)
+			defer cancel()
+		}
+
+		_, err := client.GetAboutCtx(ctx)
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("%s: got %v, want an error matching DeadlineExceeded", tt.name, err)
+		}
+		if got := attempts.Load(); got != tt.attempts {
+			t.Errorf("%s: got %d attempts, want %d", tt.name, got, tt.attempts)
+		}
+	}
+}
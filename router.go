@@ -0,0 +1,293 @@
+package signalmgr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/DonovanDiamond/signalmgr/signaltypes"
+)
+
+// Kind of event a MessageResponse carries.
+type EventKind int
+
+const (
+	// Envelope without any of the known messages, e.g. an empty sync message.
+	EventUnknown EventKind = iota
+	// Data message with text, attachments, a sticker or shared contacts.
+	EventText
+	// Reaction added to or removed from a message.
+	EventReaction
+	// Delivery, read or viewed receipt.
+	EventReceipt
+	// Typing started or stopped.
+	EventTyping
+	// Edit of a previously sent message.
+	EventEdit
+	// Remote delete of a previously sent message.
+	EventDelete
+	// Group was created or its details or members changed.
+	EventGroupUpdate
+	// Message sent by this account from another linked device.
+	EventSyncSent
+	// Story posted by a contact.
+	EventStory
+	// Voice or video call signalling.
+	EventCall
+)
+
+func (k EventKind) String() string {
+	switch k {
+	case EventUnknown:
+		return "unknown"
+	case EventText:
+		return "text"
+	case EventReaction:
+		return "reaction"
+	case EventReceipt:
+		return "receipt"
+	case EventTyping:
+		return "typing"
+	case EventEdit:
+		return "edit"
+	case EventDelete:
+		return "delete"
+	case EventGroupUpdate:
+		return "group update"
+	case EventSyncSent:
+		return "sync sent"
+	case EventStory:
+		return "story"
+	case EventCall:
+		return "call"
+	}
+	return fmt.Sprintf("EventKind(%d)", int(k))
+}
+
+// Classifies the envelope of m into the kind of event it carries.
+func (m MessageResponse) Kind() EventKind {
+	e := m.Envelope
+	d := e.DataMessage
+	switch {
+	case e.EditMessage.TargetSentTimestamp != 0:
+		return EventEdit
+	case e.SyncMessage.SentMessage.Timestamp != 0:
+		return EventSyncSent
+	case d.Reaction.Emoji != "":
+		return EventReaction
+	case d.RemoteDelete.Timestamp != 0:
+		return EventDelete
+	case d.GroupInfo.Type == "UPDATE":
+		return EventGroupUpdate
+	case d.Message != "" || len(d.Attachments) > 0 || d.Sticker.PackId != "" || len(d.Contacts) > 0:
+		return EventText
+	case len(e.ReceiptMessage.Timestamps) > 0:
+		return EventReceipt
+	case e.TypingMessag.Action != "":
+		return EventTyping
+	case e.StoryMessage.FileAttachment.Id != "" || e.StoryMessage.TextAttachment.Text != "":
+		return EventStory
+	case e.CallMessage.OfferMessage.Id != 0 ||
+		e.CallMessage.AnswerMessage.Id != 0 ||
+		e.CallMessage.BusyMessage.Id != 0 ||
+		e.CallMessage.HangupMessage.Id != 0 ||
+		len(e.CallMessage.IceUpdateMessages) > 0:
+		return EventCall
+	}
+	return EventUnknown
+}
+
+// Handles a received message.
+type HandlerFunc func(ctx context.Context, m MessageResponse) error
+
+// Wraps a handler, e.g. to log, filter or recover from panics. Middleware may skip calling next to drop a message.
+type Middleware func(next HandlerFunc) HandlerFunc
+
+// A Router classifies each MessageResponse with Kind and calls the handlers registered for that kind.
+//
+// The zero value is ready to use. Routers are safe for concurrent use.
+type Router struct {
+	// Called by Run when handling a message fails. If nil, errors are ignored.
+	ErrorHandler func(ctx context.Context, m MessageResponse, err error)
+
+	mu         sync.RWMutex
+	middleware []Middleware
+	handlers   map[EventKind][]HandlerFunc
+	accounts   map[string]*Router
+}
+
+// Creates a new, empty Router.
+func NewRouter() *Router {
+	return &Router{}
+}
+
+// Adds middleware that wraps every handler of this router and its account routers. Middleware runs in the order it was added.
+func (r *Router) Use(middleware ...Middleware) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.middleware = append(r.middleware, middleware...)
+}
+
+// Returns the router for messages received by the account with the given number, creating it if needed.
+//
+// Its handlers run after the handlers of r, wrapped in the middleware of both.
+func (r *Router) Account(number string) *Router {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.accounts == nil {
+		r.accounts = map[string]*Router{}
+	}
+	sub, ok := r.accounts[number]
+	if !ok {
+		sub = &Router{}
+		r.accounts[number] = sub
+	}
+	return sub
+}
+
+// Registers handler for messages of the given kind.
+func (r *Router) Handle(kind EventKind, handler HandlerFunc) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.handlers == nil {
+		r.handlers = map[EventKind][]HandlerFunc{}
+	}
+	r.handlers[kind] = append(r.handlers[kind], handler)
+}
+
+// Registers handler for data messages with text, attachments, a sticker or shared contacts.
+func (r *Router) OnText(handler func(ctx context.Context, m MessageResponse, msg signaltypes.DataMessage) error) {
+	r.Handle(EventText, func(ctx context.Context, m MessageResponse) error {
+		return handler(ctx, m, m.Envelope.DataMessage)
+	})
+}
+
+// Registers handler for reactions being added or removed.
+func (r *Router) OnReaction(handler func(ctx context.Context, m MessageResponse, reaction signaltypes.Reaction) error) {
+	r.Handle(EventReaction, func(ctx context.Context, m MessageResponse) error {
+		return handler(ctx, m, m.Envelope.DataMessage.Reaction)
+	})
+}
+
+// Registers handler for delivery, read and viewed receipts.
+func (r *Router) OnReceipt(handler func(ctx context.Context, m MessageResponse, receipt signaltypes.ReceiptMessage) error) {
+	r.Handle(EventReceipt, func(ctx context.Context, m MessageResponse) error {
+		return handler(ctx, m, m.Envelope.ReceiptMessage)
+	})
+}
+
+// Registers handler for typing indicators.
+func (r *Router) OnTyping(handler func(ctx context.Context, m MessageResponse, typing signaltypes.TypingMessage) error) {
+	r.Handle(EventTyping, func(ctx context.Context, m MessageResponse) error {
+		return handler(ctx, m, m.Envelope.TypingMessag)
+	})
+}
+
+// Registers handler for edits of previously sent messages.
+func (r *Router) OnEdit(handler func(ctx context.Context, m MessageResponse, edit signaltypes.EditMessage) error) {
+	r.Handle(EventEdit, func(ctx context.Context, m MessageResponse) error {
+		return handler(ctx, m, m.Envelope.EditMessage)
+	})
+}
+
+// Registers handler for remote deletes of previously sent messages.
+func (r *Router) OnDelete(handler func(ctx context.Context, m MessageResponse, del signaltypes.RemoteDelete) error) {
+	r.Handle(EventDelete, func(ctx context.Context, m MessageResponse) error {
+		return handler(ctx, m, m.Envelope.DataMessage.RemoteDelete)
+	})
+}
+
+// Registers handler for group updates.
+func (r *Router) OnGroupUpdate(handler func(ctx context.Context, m MessageResponse, group signaltypes.GroupInfo) error) {
+	r.Handle(EventGroupUpdate, func(ctx context.Context, m MessageResponse) error {
+		return handler(ctx, m, m.Envelope.DataMessage.GroupInfo)
+	})
+}
+
+// Registers handler for messages sent by this account from another linked device.
+func (r *Router) OnSyncSent(handler func(ctx context.Context, m MessageResponse, sent signaltypes.SyncDataMessage) error) {
+	r.Handle(EventSyncSent, func(ctx context.Context, m MessageResponse) error {
+		return handler(ctx, m, m.Envelope.SyncMessage.SentMessage)
+	})
+}
+
+// Registers handler for stories.
+func (r *Router) OnStory(handler func(ctx context.Context, m MessageResponse, story signaltypes.StoryMessage) error) {
+	r.Handle(EventStory, func(ctx context.Context, m MessageResponse) error {
+		return handler(ctx, m, m.Envelope.StoryMessage)
+	})
+}
+
+// Registers handler for call signalling.
+func (r *Router) OnCall(handler func(ctx context.Context, m MessageResponse, call signaltypes.CallMessage) error) {
+	r.Handle(EventCall, func(ctx context.Context, m MessageResponse) error {
+		return handler(ctx, m, m.Envelope.CallMessage)
+	})
+}
+
+// Calls the handlers registered for the kind of m, followed by those of the router for m.Account.
+//
+// All handlers are called even if one fails, the returned error joins their errors.
+//
+// If m was received by a client with a Tracer, the handlers run in a span continuing the message's trace.
+func (r *Router) Dispatch(ctx context.Context, m MessageResponse) (err error) {
+	if m.client == nil || m.client.Tracer == nil {
+		return r.dispatch(ctx, m)
+	}
+	ctx, span := m.client.Tracer.Start(m.TraceContext(ctx), "signal dispatch")
+	setMessageAttributes(span, m)
+	defer func() {
+		if err != nil {
+			span.RecordError(err)
+		}
+		span.End()
+	}()
+	return r.dispatch(ctx, m)
+}
+
+func (r *Router) dispatch(ctx context.Context, m MessageResponse) error {
+	r.mu.RLock()
+	handlers := r.handlers[m.Kind()]
+	sub := r.accounts[m.Account]
+	middleware := r.middleware
+	r.mu.RUnlock()
+
+	var h HandlerFunc = func(ctx context.Context, m MessageResponse) error {
+		var errs []error
+		for _, handler := range handlers {
+			if err := handler(ctx, m); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		if sub != nil {
+			if err := sub.dispatch(ctx, m); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		return errors.Join(errs...)
+	}
+	for i := len(middleware) - 1; i >= 0; i-- {
+		h = middleware[i](h)
+	}
+	return h(ctx, m)
+}
+
+// Dispatches every message received on the `messages` channel, passing errors to ErrorHandler.
+//
+// Will only return once ctx is done or the channel is closed.
+func (r *Router) Run(ctx context.Context, messages <-chan MessageResponse) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case m, ok := <-messages:
+			if !ok {
+				return nil
+			}
+			if err := r.Dispatch(ctx, m); err != nil && r.ErrorHandler != nil {
+				r.ErrorHandler(ctx, m, err)
+			}
+		}
+	}
+}
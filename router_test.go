@@ -0,0 +1,83 @@
+package signalmgr_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/DonovanDiamond/signalmgr"
+	"github.com/DonovanDiamond/signalmgr/signalmgrtest"
+	"github.com/DonovanDiamond/signalmgr/signaltypes"
+)
+
+func TestKind(t *testing.T) {
+	var empty, typing, sync, story, call, group, sticker signaltypes.MessageEnvelope
+	typing.TypingMessag.Action = "STARTED"
+	sync.SyncMessage.SentMessage.Timestamp = 1
+	sync.SyncMessage.SentMessage.Message = "from another device"
+	story.StoryMessage.TextAttachment.Text = "story"
+	call.CallMessage.OfferMessage.Id = 1
+	group.DataMessage.GroupInfo.Type = "UPDATE"
+	sticker.DataMessage.Sticker.PackId = "pack"
+	edit := signalmgrtest.EditEnvelope(otherNumber, 1, "edited")
+
+	tests := []struct {
+		name     string
+		envelope signaltypes.MessageEnvelope
+		want     signalmgr.EventKind
+	}{
+		{"empty", empty, signalmgr.EventUnknown},
+		{"text", signalmgrtest.TextEnvelope(otherNumber, "hi"), signalmgr.EventText},
+		{"sticker", sticker, signalmgr.EventText},
+		{"reaction", signalmgrtest.ReactionEnvelope(otherNumber, "👍", testNumber, 1), signalmgr.EventReaction},
+		{"receipt", signalmgrtest.ReceiptEnvelope(otherNumber, "read", 1), signalmgr.EventReceipt},
+		{"typing", typing, signalmgr.EventTyping},
+		// Edits carry text too, but are classified as edits.
+		{"edit", edit, signalmgr.EventEdit},
+		{"delete", signalmgrtest.DeleteEnvelope(otherNumber, 1), signalmgr.EventDelete},
+		{"group update", group, signalmgr.EventGroupUpdate},
+		{"sync sent", sync, signalmgr.EventSyncSent},
+		{"story", story, signalmgr.EventStory},
+		{"call", call, signalmgr.EventCall},
+	}
+	for _, tt := range tests {
+		m := signalmgr.MessageResponse{Envelope: tt.envelope}
+		if got := m.Kind(); got != tt.want {
+			t.Errorf("%s: Kind() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRouterDispatch(t *testing.T) {
+	r := signalmgr.NewRouter()
+	var calls []string
+	r.Use(func(next signalmgr.HandlerFunc) signalmgr.HandlerFunc {
+		return func(ctx context.Context, m signalmgr.MessageResponse) error {
+			calls = append(calls, "middleware")
+			return next(ctx, m)
+		}
+	})
+	r.OnText(func(ctx context.Context, m signalmgr.MessageResponse, msg signaltypes.DataMessage) error {
+		calls = append(calls, "text "+msg.Message)
+		return errors.New("first failed")
+	})
+	r.Account(testNumber).Handle(signalmgr.EventText, func(ctx context.Context, m signalmgr.MessageResponse) error {
+		calls = append(calls, "account")
+		return nil
+	})
+	r.OnReaction(func(ctx context.Context, m signalmgr.MessageResponse, reaction signaltypes.Reaction) error {
+		t.Error("reaction handler called for a text message")
+		return nil
+	})
+
+	m := signalmgr.MessageResponse{Envelope: signalmgrtest.TextEnvelope(otherNumber, "hi"), Account: testNumber}
+	err := r.Dispatch(context.Background(), m)
+	if err == nil || err.Error() != "first failed" {
+		t.Errorf("got error %v, want the text handler's", err)
+	}
+	want := []string{"middleware", "text hi", "account"}
+	if !slices.Equal(calls, want) {
+		t.Errorf("got calls %q, want %q", calls, want)
+	}
+}
@@ -0,0 +1,113 @@
+package signalmgr
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Result of sending a message, identifying it for follow-up edits, deletes, reactions and quotes.
+type SendResult struct {
+	// Sent timestamp in milliseconds.
+	Timestamp int64
+	// Time the message was sent.
+	Time time.Time
+	// Number of the account that sent the message.
+	Sender string
+	// Recipients the message was sent to, as passed to PostSend.
+	Recipients []string
+	// Text of the message.
+	Message string
+	// Timestamp of the message this one edited, or zero if it is not an edit.
+	EditOf int64
+
+	client *Client
+}
+
+// Decodes the REST API's response, which has the timestamp as a string.
+func (r *SendResult) UnmarshalJSON(data []byte) error {
+	var resp struct {
+		Timestamp json.RawMessage `json:"timestamp"`
+	}
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return err
+	}
+	var text string
+	if err := json.Unmarshal(resp.Timestamp, &text); err != nil {
+		text = string(resp.Timestamp)
+	}
+	timestamp, err := strconv.ParseInt(text, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid send timestamp %s: %w", resp.Timestamp, err)
+	}
+	r.Timestamp = timestamp
+	r.Time = time.UnixMilli(timestamp)
+	return nil
+}
+
+// Fills in what the REST API does not return from the message that was sent.
+func (r *SendResult) sent(c *Client, msg SendMessageV2) {
+	r.Sender = msg.Number
+	r.Recipients = msg.Recipients
+	r.Message = msg.Message
+	if msg.EditTimestamp != nil {
+		r.EditOf = *msg.EditTimestamp
+	}
+	r.client = c
+}
+
+// Returns the timestamp that identifies the message: that of the original message for edits.
+func (r SendResult) target() int64 {
+	return cmp.Or(r.EditOf, r.Timestamp)
+}
+
+func (r SendResult) account() *Account {
+	return &Account{Number: r.Sender, Client: r.client}
+}
+
+// Starts an edit of the message, to the same recipients. Add the new content and call Send. See Account.EditMessage.
+func (r SendResult) Edit() *MessageBuilder {
+	return r.account().EditMessage(r)
+}
+
+// Starts a message to the same recipients, quoting the message. Add the content and call Send.
+func (r SendResult) Quote() *MessageBuilder {
+	return r.account().NewMessage(r.Recipients...).Quote(r.target(), r.Sender, r.Message)
+}
+
+// Reacts to the message with emoji, in the conversation of every recipient.
+func (r SendResult) React(ctx context.Context, emoji string) error {
+	var errs []error
+	for _, recipient := range r.Recipients {
+		err := r.account().PostReactionCtx(ctx, struct {
+			Reaction     string `json:"reaction"`
+			Recipient    string `json:"recipient"`
+			TargetAuthor string `json:"target_author"`
+			Timestamp    int64  `json:"timestamp"`
+		}{
+			Reaction:     emoji,
+			Recipient:    recipient,
+			TargetAuthor: r.Sender,
+			Timestamp:    r.target(),
+		})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to react in %s: %w", recipient, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Deletes the message for every recipient, see Account.DeleteMessage.
+func (r SendResult) Delete(ctx context.Context) error {
+	var errs []error
+	for _, recipient := range r.Recipients {
+		if err := r.account().DeleteMessageCtx(ctx, recipient, r.target()); err != nil {
+			errs = append(errs, fmt.Errorf("failed to delete in %s: %w", recipient, err))
+		}
+	}
+	return errors.Join(errs...)
+}
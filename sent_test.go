@@ -0,0 +1,34 @@
+package signalmgr_test
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/DonovanDiamond/signalmgr/signalmgrtest"
+)
+
+func TestSend(t *testing.T) {
+	srv := signalmgrtest.NewServer(testNumber)
+	defer srv.Close()
+	ctx := context.Background()
+
+	result, err := srv.Account(testNumber).NewMessage(otherNumber).Text("hello ").Bold("world").Attach("aGk=").Send(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sent := srv.Sent()
+	if len(sent) != 1 {
+		t.Fatalf("got %d messages sent, want 1", len(sent))
+	}
+	msg := sent[0]
+	if msg.Number != testNumber || !slices.Equal(msg.Recipients, []string{otherNumber}) || msg.Message != "hello **world**" {
+		t.Errorf("sent %+v", msg.SendMessageV2)
+	}
+	if msg.TextMode == nil || *msg.TextMode != "styled" || !slices.Equal(msg.Base64Attachments, []string{"aGk="}) {
+		t.Errorf("sent %+v", msg.SendMessageV2)
+	}
+	if result.Timestamp != msg.Timestamp || result.Sender != testNumber || result.Message != "hello **world**" {
+		t.Errorf("got result %+v for timestamp %d", result, msg.Timestamp)
+	}
+}
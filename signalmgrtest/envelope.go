@@ -0,0 +1,70 @@
+package signalmgrtest
+
+import (
+	"strings"
+
+	"github.com/DonovanDiamond/signalmgr"
+	"github.com/DonovanDiamond/signalmgr/signaltypes"
+)
+
+// Returns the envelope of a text message from sender, for Server.Deliver.
+func TextEnvelope(sender, text string) signaltypes.MessageEnvelope {
+	var e signaltypes.MessageEnvelope
+	e.SourceNumber = sender
+	e.DataMessage.Message = text
+	return e
+}
+
+// Returns the envelope of a text message from sender in group, for Server.Deliver.
+func GroupTextEnvelope(sender string, group signalmgr.Group, text string) signaltypes.MessageEnvelope {
+	e := TextEnvelope(sender, text)
+	e.DataMessage.GroupInfo = signaltypes.GroupInfo{
+		GroupId:   group.InternalID,
+		GroupName: group.Name,
+		Type:      "DELIVER",
+	}
+	return e
+}
+
+// Returns the envelope of sender's reaction with emoji to the message author sent at timestamp, for Server.Deliver.
+func ReactionEnvelope(sender, emoji, author string, timestamp int64) signaltypes.MessageEnvelope {
+	var e signaltypes.MessageEnvelope
+	e.SourceNumber = sender
+	e.DataMessage.Reaction = signaltypes.Reaction{
+		Emoji:               emoji,
+		TargetAuthor:        author,
+		TargetAuthorNumber:  author,
+		TargetSentTimestamp: timestamp,
+	}
+	return e
+}
+
+// Returns the envelope of sender's edit of their message sent at timestamp, for Server.Deliver.
+func EditEnvelope(sender string, timestamp int64, text string) signaltypes.MessageEnvelope {
+	var e signaltypes.MessageEnvelope
+	e.SourceNumber = sender
+	e.EditMessage.TargetSentTimestamp = timestamp
+	e.EditMessage.DataMessage.Message = text
+	return e
+}
+
+// Returns the envelope of sender's remote delete of their message sent at timestamp, for Server.Deliver.
+func DeleteEnvelope(sender string, timestamp int64) signaltypes.MessageEnvelope {
+	var e signaltypes.MessageEnvelope
+	e.SourceNumber = sender
+	e.DataMessage.RemoteDelete.Timestamp = timestamp
+	return e
+}
+
+// Returns the envelope of a receipt from sender for the messages sent at timestamps, for Server.Deliver. kind is `delivery`, `read` or `viewed`.
+func ReceiptEnvelope(sender, kind string, timestamps ...int64) signaltypes.MessageEnvelope {
+	var e signaltypes.MessageEnvelope
+	e.SourceNumber = sender
+	e.ReceiptMessage = signaltypes.ReceiptMessage{
+		IsDelivery: strings.EqualFold(kind, "delivery"),
+		IsRead:     strings.EqualFold(kind, "read"),
+		IsViewed:   strings.EqualFold(kind, "viewed"),
+		Timestamps: timestamps,
+	}
+	return e
+}
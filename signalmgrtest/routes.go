@@ -0,0 +1,489 @@
+package signalmgrtest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/png"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/DonovanDiamond/signalmgr"
+	"github.com/gorilla/websocket"
+)
+
+var upgrader = websocket.Upgrader{
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// Returns a blank PNG standing in for the QR code the REST API serves to link a device.
+func qrCodePNG() []byte {
+	var buf bytes.Buffer
+	png.Encode(&buf, image.NewGray(image.Rect(0, 0, 8, 8)))
+	return buf.Bytes()
+}
+
+// Writes an error the way the REST API does.
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
+
+// Decodes the JSON body of r into v, writing an error and returning false if it is invalid.
+func decode(w http.ResponseWriter, r *http.Request, v any) bool {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		writeError(w, http.StatusBadRequest, "Couldn't process request - invalid request")
+		return false
+	}
+	return true
+}
+
+// Adds members to list, skipping those already in it.
+func addMembers(list []string, members ...string) []string {
+	for _, member := range members {
+		if !slices.Contains(list, member) {
+			list = append(list, member)
+		}
+	}
+	return list
+}
+
+// Removes members from list.
+func removeMembers(list []string, members ...string) []string {
+	return slices.DeleteFunc(list, func(member string) bool { return slices.Contains(members, member) })
+}
+
+// A handler for routes under an account number, called with the lock held.
+type accountHandler func(w http.ResponseWriter, r *http.Request, a *account)
+
+// Looks up the account of the number path value, failing like the REST API does for unknown numbers.
+func (s *Server) withAccount(h accountHandler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		number := r.PathValue("number")
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		a := s.accounts[number]
+		if a == nil {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("User %s is not registered.", number))
+			return
+		}
+		h(w, r, a)
+	}
+}
+
+// Looks up the group of the groupid path value.
+func (s *Server) withGroup(h func(w http.ResponseWriter, r *http.Request, a *account, group *signalmgr.Group)) http.HandlerFunc {
+	return s.withAccount(func(w http.ResponseWriter, r *http.Request, a *account) {
+		id := r.PathValue("groupid")
+		i := slices.IndexFunc(a.groups, func(g *signalmgr.Group) bool { return g.ID == id })
+		if i < 0 {
+			writeError(w, http.StatusNotFound, fmt.Sprintf("Group %s not found", id))
+			return
+		}
+		h(w, r, a, a.groups[i])
+	})
+}
+
+// Responds with no content, for routes that only change state the Server does not keep.
+func (s *Server) noContent(w http.ResponseWriter, r *http.Request, a *account) {
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) routes() http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /v1/about", func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		writeJSON(w, http.StatusOK, signalmgr.GetAboutResponse{
+			Build:        2,
+			Capabilities: map[string][]string{"v2/send": {"quotes", "mentions"}},
+			Mode:         s.mode,
+			Version:      "signalmgrtest",
+			Versions:     []string{"v1", "v2"},
+		})
+	})
+	mux.HandleFunc("GET /v1/health", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mux.HandleFunc("POST /v1/configuration", func(w http.ResponseWriter, r *http.Request) {
+		var config signalmgr.Configuration
+		if !decode(w, r, &config) {
+			return
+		}
+		s.mu.Lock()
+		s.config = config
+		s.mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mux.HandleFunc("GET /v1/search", func(w http.ResponseWriter, r *http.Request) {
+		numbers := r.URL.Query()["numbers"]
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		results := []signalmgr.SearchResult{}
+		for _, number := range numbers {
+			results = append(results, signalmgr.SearchResult{Number: number, Registered: s.registered(number)})
+		}
+		writeJSON(w, http.StatusOK, results)
+	})
+
+	mux.HandleFunc("GET /v1/qrcodelink", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("device_name") == "" {
+			writeError(w, http.StatusBadRequest, "Please provide a name for the device")
+			return
+		}
+		w.Header().Set("Content-Type", "image/png")
+		w.Write(qrCodePNG())
+	})
+
+	// Accounts.
+	mux.HandleFunc("GET /v1/accounts", func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		numbers := []string{}
+		for number := range s.accounts {
+			numbers = append(numbers, number)
+		}
+		slices.Sort(numbers)
+		writeJSON(w, http.StatusOK, numbers)
+	})
+	mux.HandleFunc("POST /v1/register/{number}", func(w http.ResponseWriter, r *http.Request) {
+		s.AddAccount(r.PathValue("number"))
+		w.WriteHeader(http.StatusCreated)
+	})
+	mux.HandleFunc("POST /v1/register/{number}/verify/{token}", s.noContentAccount())
+	mux.HandleFunc("POST /v1/unregister/{number}", s.withAccount(func(w http.ResponseWriter, r *http.Request, a *account) {
+		for _, c := range a.conns {
+			go c.close()
+		}
+		delete(s.accounts, a.number)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	mux.HandleFunc("POST /v1/devices/{number}", s.noContentAccount())
+	mux.HandleFunc("GET /v1/configuration/{number}/settings", s.withAccount(func(w http.ResponseWriter, r *http.Request, a *account) {
+		writeJSON(w, http.StatusOK, a.settings)
+	}))
+	mux.HandleFunc("POST /v1/configuration/{number}/settings", s.withAccount(func(w http.ResponseWriter, r *http.Request, a *account) {
+		if decode(w, r, &a.settings) {
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	mux.HandleFunc("POST /v1/accounts/{number}/rate-limit-challenge", s.noContentAccount())
+	mux.HandleFunc("PUT /v1/accounts/{number}/settings", s.noContentAccount())
+	mux.HandleFunc("POST /v1/accounts/{number}/username", s.withAccount(func(w http.ResponseWriter, r *http.Request, a *account) {
+		var data struct {
+			Username string `json:"username"`
+		}
+		if !decode(w, r, &data) {
+			return
+		}
+		a.username = data.Username
+		if !strings.Contains(a.username, ".") {
+			a.username += ".01"
+		}
+		writeJSON(w, http.StatusOK, signalmgr.Account_PostUsernameResponse{
+			Username:     a.username,
+			UsernameLink: "https://signal.me/#eu/" + a.username,
+		})
+	}))
+	mux.HandleFunc("DELETE /v1/accounts/{number}/username", s.withAccount(func(w http.ResponseWriter, r *http.Request, a *account) {
+		a.username = ""
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	mux.HandleFunc("PUT /v1/profiles/{number}", s.noContentAccount())
+
+	// Messages.
+	mux.HandleFunc("POST /v2/send", s.send)
+	mux.HandleFunc("GET /v1/receive/{number}", s.receive)
+	mux.HandleFunc("POST /v1/reactions/{number}", s.noContentAccount())
+	mux.HandleFunc("DELETE /v1/reactions/{number}", s.noContentAccount())
+	mux.HandleFunc("POST /v1/receipts/{number}", s.noContentAccount())
+	mux.HandleFunc("PUT /v1/typing-indicator/{number}", s.noContentAccount())
+	mux.HandleFunc("DELETE /v1/typing-indicator/{number}", s.noContentAccount())
+	mux.HandleFunc("DELETE /v1/remote-delete/{number}", s.withAccount(func(w http.ResponseWriter, r *http.Request, a *account) {
+		writeJSON(w, http.StatusOK, map[string]string{"timestamp": strconv.FormatInt(s.now(), 10)})
+	}))
+
+	// Groups.
+	mux.HandleFunc("GET /v1/groups/{number}", s.withAccount(func(w http.ResponseWriter, r *http.Request, a *account) {
+		groups := []signalmgr.Group{}
+		for _, group := range a.groups {
+			groups = append(groups, *group)
+		}
+		writeJSON(w, http.StatusOK, groups)
+	}))
+	mux.HandleFunc("POST /v1/groups/{number}", s.withAccount(func(w http.ResponseWriter, r *http.Request, a *account) {
+		var data struct {
+			Members []string `json:"members"`
+			Name    string   `json:"name"`
+		}
+		if !decode(w, r, &data) {
+			return
+		}
+		group := s.addGroup(a, signalmgr.Group{
+			Name:    data.Name,
+			Members: data.Members,
+			Admins:  []string{a.number},
+		})
+		writeJSON(w, http.StatusCreated, map[string]string{"id": group.ID})
+	}))
+	mux.HandleFunc("GET /v1/groups/{number}/{groupid}", s.withGroup(func(w http.ResponseWriter, r *http.Request, a *account, group *signalmgr.Group) {
+		writeJSON(w, http.StatusOK, group)
+	}))
+	mux.HandleFunc("PUT /v1/groups/{number}/{groupid}", s.withGroup(func(w http.ResponseWriter, r *http.Request, a *account, group *signalmgr.Group) {
+		var data struct {
+			Name string `json:"name"`
+		}
+		if !decode(w, r, &data) {
+			return
+		}
+		if data.Name != "" {
+			group.Name = data.Name
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	mux.HandleFunc("DELETE /v1/groups/{number}/{groupid}", s.withGroup(func(w http.ResponseWriter, r *http.Request, a *account, group *signalmgr.Group) {
+		a.groups = slices.DeleteFunc(a.groups, func(g *signalmgr.Group) bool { return g == group })
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	groupList := func(list func(group *signalmgr.Group) *[]string, field string, update func([]string, ...string) []string) http.HandlerFunc {
+		return s.withGroup(func(w http.ResponseWriter, r *http.Request, a *account, group *signalmgr.Group) {
+			var data map[string][]string
+			if !decode(w, r, &data) {
+				return
+			}
+			*list(group) = update(*list(group), data[field]...)
+			w.WriteHeader(http.StatusNoContent)
+		})
+	}
+	admins := func(group *signalmgr.Group) *[]string { return &group.Admins }
+	members := func(group *signalmgr.Group) *[]string { return &group.Members }
+	mux.HandleFunc("POST /v1/groups/{number}/{groupid}/admins", groupList(admins, "admins", addMembers))
+	mux.HandleFunc("DELETE /v1/groups/{number}/{groupid}/admins", groupList(admins, "admins", removeMembers))
+	mux.HandleFunc("POST /v1/groups/{number}/{groupid}/members", groupList(members, "members", addMembers))
+	mux.HandleFunc("DELETE /v1/groups/{number}/{groupid}/members", groupList(members, "members", removeMembers))
+	mux.HandleFunc("POST /v1/groups/{number}/{groupid}/block", s.withGroup(func(w http.ResponseWriter, r *http.Request, a *account, group *signalmgr.Group) {
+		group.Blocked = true
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	mux.HandleFunc("POST /v1/groups/{number}/{groupid}/join", s.withGroup(func(w http.ResponseWriter, r *http.Request, a *account, group *signalmgr.Group) {
+		group.Members = addMembers(group.Members, a.number)
+		group.PendingInvites = removeMembers(group.PendingInvites, a.number)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	mux.HandleFunc("POST /v1/groups/{number}/{groupid}/quit", s.withGroup(func(w http.ResponseWriter, r *http.Request, a *account, group *signalmgr.Group) {
+		group.Members = removeMembers(group.Members, a.number)
+		group.Admins = removeMembers(group.Admins, a.number)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	// Contacts and identities.
+	mux.HandleFunc("GET /v1/contacts/{number}", s.withAccount(func(w http.ResponseWriter, r *http.Request, a *account) {
+		writeJSON(w, http.StatusOK, append([]signalmgr.Contact{}, a.contacts...))
+	}))
+	mux.HandleFunc("POST /v1/contacts/{number}", s.withAccount(func(w http.ResponseWriter, r *http.Request, a *account) {
+		var data struct {
+			ExpirationInSeconds int    `json:"expiration_in_seconds"`
+			Name                string `json:"name"`
+			Recipient           string `json:"recipient"`
+		}
+		if !decode(w, r, &data) {
+			return
+		}
+		i := slices.IndexFunc(a.contacts, func(c signalmgr.Contact) bool { return c.Number == data.Recipient })
+		if i < 0 {
+			a.contacts = append(a.contacts, signalmgr.Contact{Number: data.Recipient})
+			i = len(a.contacts) - 1
+		}
+		a.contacts[i].Name = data.Name
+		a.contacts[i].MessageExpiration = strconv.Itoa(data.ExpirationInSeconds)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	mux.HandleFunc("PUT /v1/contacts/{number}/sync", s.noContentAccount())
+	mux.HandleFunc("GET /v1/identities/{number}", s.withAccount(func(w http.ResponseWriter, r *http.Request, a *account) {
+		writeJSON(w, http.StatusOK, append([]signalmgr.Identity{}, a.identities...))
+	}))
+	mux.HandleFunc("PUT /v1/identities/{number}/trust/{trusted}", s.withAccount(func(w http.ResponseWriter, r *http.Request, a *account) {
+		trusted := r.PathValue("trusted")
+		i := slices.IndexFunc(a.identities, func(i signalmgr.Identity) bool { return i.Number == trusted })
+		if i < 0 {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("No identity found for %s", trusted))
+			return
+		}
+		a.identities[i].Status = "TRUSTED_VERIFIED"
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	mux.HandleFunc("GET /v1/sticker-packs/{number}", s.withAccount(func(w http.ResponseWriter, r *http.Request, a *account) {
+		writeJSON(w, http.StatusOK, append([]signalmgr.StickerPack{}, a.stickers...))
+	}))
+	mux.HandleFunc("POST /v1/sticker-packs/{number}", s.withAccount(func(w http.ResponseWriter, r *http.Request, a *account) {
+		var data struct {
+			PackID string `json:"pack_id"`
+		}
+		if !decode(w, r, &data) {
+			return
+		}
+		a.stickers = append(a.stickers, signalmgr.StickerPack{PackID: data.PackID, Installed: true})
+		w.WriteHeader(http.StatusCreated)
+	}))
+
+	// Attachments.
+	mux.HandleFunc("GET /v1/attachments", func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		ids := []string{}
+		for id := range s.attachments {
+			ids = append(ids, id)
+		}
+		slices.Sort(ids)
+		writeJSON(w, http.StatusOK, ids)
+	})
+	mux.HandleFunc("GET /v1/attachments/{id}", func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		attachment, ok := s.attachments[r.PathValue("id")]
+		s.mu.Unlock()
+		if !ok {
+			writeError(w, http.StatusNotFound, "attachment not found")
+			return
+		}
+		w.Header().Set("Content-Type", attachment.ContentType)
+		w.Header().Set("Content-Length", strconv.Itoa(len(attachment.Data)))
+		w.Write(attachment.Data)
+	})
+	mux.HandleFunc("DELETE /v1/attachments/{id}", func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		id := r.PathValue("id")
+		if _, ok := s.attachments[id]; !ok {
+			writeError(w, http.StatusNotFound, "attachment not found")
+			return
+		}
+		delete(s.attachments, id)
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	return mux
+}
+
+func (s *Server) noContentAccount() http.HandlerFunc {
+	return s.withAccount(s.noContent)
+}
+
+// Reports whether number is known to the Server as an account, contact or group member.
+func (s *Server) registered(number string) bool {
+	for _, a := range s.accounts {
+		if a.number == number || slices.ContainsFunc(a.contacts, func(c signalmgr.Contact) bool { return c.Number == number }) {
+			return true
+		}
+		for _, group := range a.groups {
+			if slices.Contains(group.Members, number) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (s *Server) send(w http.ResponseWriter, r *http.Request) {
+	var msg signalmgr.SendMessageV2
+	if !decode(w, r, &msg) {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	a := s.accounts[msg.Number]
+	switch {
+	case a == nil:
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("User %s is not registered.", msg.Number))
+		return
+	case len(msg.Recipients) == 0:
+		writeError(w, http.StatusBadRequest, "Couldn't process request - please provide at least one recipient")
+		return
+	case msg.Message == "" && len(msg.Base64Attachments) == 0 && msg.Sticker == "":
+		writeError(w, http.StatusBadRequest, "Couldn't process request - message is empty")
+		return
+	}
+	for _, recipient := range msg.Recipients {
+		if strings.HasPrefix(recipient, "group.") && !slices.ContainsFunc(a.groups, func(g *signalmgr.Group) bool { return g.ID == recipient }) {
+			writeError(w, http.StatusNotFound, fmt.Sprintf("Failed to send message: Group %s not found", recipient))
+			return
+		}
+	}
+
+	timestamp := s.now()
+	s.sent = append(s.sent, SentMessage{SendMessageV2: msg, Timestamp: timestamp})
+	close(s.changed)
+	s.changed = make(chan struct{})
+	writeJSON(w, http.StatusCreated, map[string]string{"timestamp": strconv.FormatInt(timestamp, 10)})
+}
+
+// Serves the receive websocket in `json-rpc` mode, or the queued messages as a JSON array otherwise.
+func (s *Server) receive(w http.ResponseWriter, r *http.Request) {
+	number := r.PathValue("number")
+	s.mu.Lock()
+	a := s.accounts[number]
+	if a == nil {
+		s.mu.Unlock()
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("User %s is not registered.", number))
+		return
+	}
+	if !websocket.IsWebSocketUpgrade(r) {
+		messages := append([]signalmgr.MessageResponse{}, a.queue...)
+		a.queue = nil
+		s.mu.Unlock()
+		writeJSON(w, http.StatusOK, messages)
+		return
+	}
+	s.mu.Unlock()
+
+	ws, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	c := &conn{ws: ws, out: make(chan []byte, 16), done: make(chan struct{})}
+
+	s.mu.Lock()
+	queue := a.queue
+	a.queue = nil
+	a.conns = append(a.conns, c)
+	s.mu.Unlock()
+
+	go func() {
+		defer c.close()
+		for _, message := range queue {
+			data, _ := json.Marshal(message)
+			if ws.WriteMessage(websocket.TextMessage, data) != nil {
+				return
+			}
+		}
+		for {
+			select {
+			case data := <-c.out:
+				if ws.WriteMessage(websocket.TextMessage, data) != nil {
+					return
+				}
+			case <-c.done:
+				return
+			}
+		}
+	}()
+
+	// Reading handles pings and notices when the client goes away.
+	for {
+		if _, _, err := ws.ReadMessage(); err != nil {
+			break
+		}
+	}
+	c.close()
+	s.mu.Lock()
+	a.conns = slices.DeleteFunc(a.conns, func(other *conn) bool { return other == c })
+	s.mu.Unlock()
+}
@@ -0,0 +1,397 @@
+// Package signalmgrtest provides an in-process fake of signal-cli-rest-api for testing code built on signalmgr without a Signal number.
+//
+// The fake keeps accounts, groups, contacts, identities and attachments in memory, lets tests inject incoming envelopes, and records every request and sent message for assertions:
+//
+//	srv := signalmgrtest.NewServer("+1234567890")
+//	defer srv.Close()
+//	account := srv.Account("+1234567890")
+//	srv.Deliver("+1234567890", signalmgrtest.TextEnvelope("+1987654321", "/ping"))
+//	sent, err := srv.WaitSent(ctx, 1)
+package signalmgrtest
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/DonovanDiamond/signalmgr"
+	"github.com/DonovanDiamond/signalmgr/signaltypes"
+	"github.com/gorilla/websocket"
+)
+
+// A request received by the Server.
+type Request struct {
+	Method string
+	// Path of the request, without the query.
+	Path  string
+	Query string
+	Body  []byte
+	Time  time.Time
+}
+
+// Decodes the JSON body of the request into v.
+func (r Request) Decode(v any) error {
+	return json.Unmarshal(r.Body, v)
+}
+
+// A message sent through the Server with PostSend.
+type SentMessage struct {
+	signalmgr.SendMessageV2
+	// Timestamp the Server assigned to the message.
+	Timestamp int64
+}
+
+// An attachment served by the Server.
+type Attachment struct {
+	Data        []byte
+	ContentType string
+}
+
+// A Server is an in-process fake of signal-cli-rest-api, serving the routes signalmgr calls over HTTP and the receive websocket.
+//
+// Its methods are safe for concurrent use.
+type Server struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	mode     string
+	clock    int64
+	accounts map[string]*account
+	config   signalmgr.Configuration
+	// Attachments by id.
+	attachments map[string]Attachment
+	requests    []Request
+	sent        []SentMessage
+	failures    []failure
+	// Closed and replaced whenever a message is sent, to wake up WaitSent.
+	changed chan struct{}
+}
+
+type account struct {
+	number     string
+	settings   signalmgr.Account_Configuration
+	username   string
+	groups     []*signalmgr.Group
+	contacts   []signalmgr.Contact
+	identities []signalmgr.Identity
+	stickers   []signalmgr.StickerPack
+	// Messages waiting to be received.
+	queue []signalmgr.MessageResponse
+	conns []*conn
+}
+
+// A receive websocket and the messages waiting to be written to it.
+type conn struct {
+	ws  *websocket.Conn
+	out chan []byte
+	// Closed when the connection is gone.
+	done chan struct{}
+	once sync.Once
+}
+
+func (c *conn) close() {
+	c.once.Do(func() {
+		close(c.done)
+		c.ws.Close()
+	})
+}
+
+type failure struct {
+	method, path string
+	status       int
+	message      string
+}
+
+// Starts a Server in `json-rpc` mode with the given accounts registered.
+func NewServer(numbers ...string) *Server {
+	s := &Server{
+		mode:        "json-rpc",
+		clock:       time.Now().UnixMilli(),
+		accounts:    map[string]*account{},
+		attachments: map[string]Attachment{},
+		changed:     make(chan struct{}),
+	}
+	for _, number := range numbers {
+		s.AddAccount(number)
+	}
+	s.Server = httptest.NewServer(s.record(s.routes()))
+	return s
+}
+
+// Closes all receive websockets and shuts the Server down.
+func (s *Server) Close() {
+	s.DropConnections()
+	s.Server.Close()
+}
+
+// Returns a client for the Server.
+func (s *Server) Client() *signalmgr.Client {
+	return signalmgr.NewClient(s.URL)
+}
+
+// Returns number's account, using a client for the Server.
+func (s *Server) Account(number string) *signalmgr.Account {
+	return s.Client().Account(number)
+}
+
+// Sets the mode reported by GetAbout: `json-rpc`, where messages are received over the websocket, or `normal` or `native`, where they are polled with GetMessages.
+func (s *Server) SetMode(mode string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.mode = mode
+}
+
+// Returns a timestamp after every timestamp returned before.
+func (s *Server) now() int64 {
+	s.clock = max(s.clock+1, time.Now().UnixMilli())
+	return s.clock
+}
+
+// Registers number as an account.
+func (s *Server) AddAccount(number string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.account(number)
+}
+
+// Returns number's account, registering it if needed.
+func (s *Server) account(number string) *account {
+	if s.accounts[number] == nil {
+		s.accounts[number] = &account{number: number, settings: signalmgr.Account_Configuration{TrustMode: "on-first-use"}}
+	}
+	return s.accounts[number]
+}
+
+// Adds group to number's groups, registering number if needed. Empty ids are generated, and number is added to the members. Returns the group as stored.
+func (s *Server) AddGroup(number string, group signalmgr.Group) signalmgr.Group {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return *s.addGroup(s.account(number), group)
+}
+
+func (s *Server) addGroup(a *account, group signalmgr.Group) *signalmgr.Group {
+	if group.InternalID == "" {
+		group.InternalID = base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("signalmgrtest-group-%d", s.now())))
+	}
+	if group.ID == "" {
+		group.ID = "group." + base64.StdEncoding.EncodeToString([]byte(group.InternalID))
+	}
+	if !slices.Contains(group.Members, a.number) {
+		group.Members = append(group.Members, a.number)
+	}
+	a.groups = append(a.groups, &group)
+	return &group
+}
+
+// Adds or replaces contact in number's contacts, registering number if needed.
+func (s *Server) AddContact(number string, contact signalmgr.Contact) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	a := s.account(number)
+	a.contacts = slices.DeleteFunc(a.contacts, func(c signalmgr.Contact) bool { return c.Number == contact.Number })
+	a.contacts = append(a.contacts, contact)
+}
+
+// Adds or replaces identity in number's identities, registering number if needed.
+func (s *Server) AddIdentity(number string, identity signalmgr.Identity) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	a := s.account(number)
+	a.identities = slices.DeleteFunc(a.identities, func(i signalmgr.Identity) bool { return i.Number == identity.Number })
+	a.identities = append(a.identities, identity)
+}
+
+// Adds an attachment served under id.
+func (s *Server) AddAttachment(id string, data []byte, contentType string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.attachments[id] = Attachment{Data: data, ContentType: contentType}
+}
+
+// Returns the attachments the Server serves, by id.
+func (s *Server) Attachments() map[string]Attachment {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return maps.Clone(s.attachments)
+}
+
+// Returns the REST API configuration last set with PostConfiguration.
+func (s *Server) Configuration() signalmgr.Configuration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.config
+}
+
+// Returns number's groups.
+func (s *Server) Groups(number string) (groups []signalmgr.Group) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if a := s.accounts[number]; a != nil {
+		for _, group := range a.groups {
+			groups = append(groups, *group)
+		}
+	}
+	return
+}
+
+// Delivers envelope to number, over its receive websockets or, if none are open, when it next receives messages.
+//
+// The envelope's timestamps are set from the Server's clock if zero. Returns the envelope as delivered.
+func (s *Server) Deliver(number string, envelope signaltypes.MessageEnvelope) signaltypes.MessageEnvelope {
+	s.mu.Lock()
+	if envelope.Timestamp == 0 {
+		envelope.Timestamp = s.now()
+	}
+	if envelope.Source == "" {
+		envelope.Source = envelope.SourceNumber
+	}
+	if envelope.ServerReceivedTimestamp == 0 {
+		envelope.ServerReceivedTimestamp = envelope.Timestamp
+		envelope.ServerDeliveredTimestamp = envelope.Timestamp
+	}
+	if envelope.DataMessage.Timestamp == 0 && (envelope.DataMessage.Message != "" || len(envelope.DataMessage.Attachments) > 0) {
+		envelope.DataMessage.Timestamp = envelope.Timestamp
+	}
+	a := s.account(number)
+	message := signalmgr.MessageResponse{Envelope: envelope, Account: number}
+	conns := slices.Clone(a.conns)
+	if len(conns) == 0 {
+		a.queue = append(a.queue, message)
+	}
+	s.mu.Unlock()
+
+	if len(conns) > 0 {
+		data, _ := json.Marshal(message)
+		for _, c := range conns {
+			select {
+			case c.out <- data:
+			case <-c.done:
+			}
+		}
+	}
+	return envelope
+}
+
+// Closes all receive websockets, as if the connection to the REST API was lost.
+func (s *Server) DropConnections() {
+	s.mu.Lock()
+	var conns []*conn
+	for _, a := range s.accounts {
+		conns = append(conns, a.conns...)
+		a.conns = nil
+	}
+	s.mu.Unlock()
+	for _, c := range conns {
+		c.close()
+	}
+}
+
+// Returns the number of receive websockets open for number.
+func (s *Server) Connections(number string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if a := s.accounts[number]; a != nil {
+		return len(a.conns)
+	}
+	return 0
+}
+
+// Makes the next request with method to path fail with status and message, in the REST API's error format. An empty method matches any method.
+func (s *Server) FailNext(method, path string, status int, message string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures = append(s.failures, failure{method, path, status, message})
+}
+
+// Returns the requests received, oldest first.
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.requests)
+}
+
+// Returns the requests received with method to path, oldest first. An empty method matches any method.
+func (s *Server) RequestsTo(method, path string) (requests []Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, r := range s.requests {
+		if (method == "" || r.Method == method) && r.Path == path {
+			requests = append(requests, r)
+		}
+	}
+	return
+}
+
+// Returns the messages sent, oldest first.
+func (s *Server) Sent() []SentMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.sent)
+}
+
+// Waits until at least n messages were sent and returns them, or returns ctx's error with the messages sent so far when ctx is done.
+func (s *Server) WaitSent(ctx context.Context, n int) ([]SentMessage, error) {
+	for {
+		s.mu.Lock()
+		sent := slices.Clone(s.sent)
+		changed := s.changed
+		s.mu.Unlock()
+		if len(sent) >= n {
+			return sent, nil
+		}
+		select {
+		case <-ctx.Done():
+			return sent, ctx.Err()
+		case <-changed:
+		}
+	}
+}
+
+// Forgets the requests and messages sent so far.
+func (s *Server) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests = nil
+	s.sent = nil
+}
+
+// Records every request, and fails those matched by FailNext.
+func (s *Server) record(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		s.mu.Lock()
+		s.requests = append(s.requests, Request{
+			Method: r.Method,
+			Path:   r.URL.Path,
+			Query:  r.URL.RawQuery,
+			Body:   body,
+			Time:   time.Now(),
+		})
+		i := slices.IndexFunc(s.failures, func(f failure) bool {
+			return (f.method == "" || f.method == r.Method) && f.path == r.URL.Path
+		})
+		var fail failure
+		if i >= 0 {
+			fail = s.failures[i]
+			s.failures = slices.Delete(s.failures, i, i+1)
+		}
+		s.mu.Unlock()
+
+		if i >= 0 {
+			writeError(w, fail.status, fail.message)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,92 @@
+package signalmgrtest_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/DonovanDiamond/signalmgr"
+	"github.com/DonovanDiamond/signalmgr/signalmgrtest"
+)
+
+const (
+	testNumber  = "+14155550123"
+	otherNumber = "+14155550199"
+)
+
+func TestServerErrors(t *testing.T) {
+	srv := signalmgrtest.NewServer(testNumber)
+	defer srv.Close()
+	account := srv.Account(testNumber)
+	ctx := context.Background()
+
+	_, err := account.GetGroupCtx(ctx, "group.missing")
+	if !errors.Is(err, signalmgr.ErrNotFound) {
+		t.Errorf("missing group: got %v, want ErrNotFound", err)
+	}
+	_, err = srv.Account(otherNumber).GetGroupsCtx(ctx)
+	if !errors.Is(err, signalmgr.ErrAccountNotRegistered) {
+		t.Errorf("unknown account: got %v, want ErrAccountNotRegistered", err)
+	}
+
+	srv.FailNext(http.MethodPost, "/v2/send", http.StatusBadRequest, "Failed to send message: [429] Rate Limit Exceeded")
+	_, err = account.NewMessage(otherNumber).Text("hi").Send(ctx)
+	var apiErr *signalmgr.APIError
+	if !errors.Is(err, signalmgr.ErrRateLimited) || !errors.As(err, &apiErr) || apiErr.Path != "/v2/send" {
+		t.Errorf("FailNext: got %v, want a rate limit APIError for /v2/send", err)
+	}
+	// FailNext only fails the next request.
+	if _, err := account.NewMessage(otherNumber).Text("hi").Send(ctx); err != nil {
+		t.Errorf("after FailNext: %v", err)
+	}
+	if sent := srv.Sent(); len(sent) != 1 {
+		t.Errorf("got %d messages sent, want 1", len(sent))
+	}
+}
+
+func TestReceiveSocket(t *testing.T) {
+	srv := signalmgrtest.NewServer(testNumber)
+	defer srv.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// Delivered before the socket is opened, so it is queued.
+	srv.Deliver(testNumber, signalmgrtest.TextEnvelope(otherNumber, "queued"))
+
+	messages := make(chan signalmgr.MessageResponse)
+	done := make(chan error, 1)
+	socketCtx, stop := context.WithCancel(ctx)
+	go func() {
+		done <- srv.Account(testNumber).GetMessagesSocketCtx(socketCtx, messages)
+	}()
+
+	receive := func() signalmgr.MessageResponse {
+		t.Helper()
+		select {
+		case m := <-messages:
+			return m
+		case <-ctx.Done():
+			t.Fatal("no message received")
+			return signalmgr.MessageResponse{}
+		}
+	}
+	m := receive()
+	if m.Account != testNumber || m.Sender() != otherNumber || m.Envelope.DataMessage.Message != "queued" {
+		t.Errorf("got %+v", m)
+	}
+	for srv.Connections(testNumber) == 0 {
+		time.Sleep(5 * time.Millisecond)
+	}
+	delivered := srv.Deliver(testNumber, signalmgrtest.TextEnvelope(otherNumber, "live"))
+	m = receive()
+	if m.Envelope.DataMessage.Message != "live" || m.Timestamp() != delivered.Timestamp {
+		t.Errorf("got %+v, want timestamp %d", m, delivered.Timestamp)
+	}
+
+	stop()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("got %v, want context.Canceled", err)
+	}
+}
@@ -0,0 +1,477 @@
+package signalmgr
+
+import (
+	"bufio"
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DonovanDiamond/signalmgr/signaltypes"
+)
+
+// A message kept in a MessageStore, with edits, remote deletes and reactions applied.
+type StoredMessage struct {
+	// Number of the account that sent or received the message.
+	Account string `json:"account"`
+	// Conversation the message belongs to: the contact's number or UUID, or the group in `group.` form.
+	Conversation string `json:"conversation"`
+	SenderNumber string `json:"sender_number,omitempty"`
+	SenderUUID   string `json:"sender_uuid,omitempty"`
+	SenderName   string `json:"sender_name,omitempty"`
+	// Sent timestamp in milliseconds, which identifies the message together with its sender.
+	Timestamp int64 `json:"timestamp"`
+	// Whether the message was sent by the account, from this or a linked device.
+	Outgoing bool `json:"outgoing"`
+	// Current text of the message. Empty once the message is deleted.
+	Text        string                   `json:"text"`
+	Attachments []signaltypes.Attachment `json:"attachments,omitempty"`
+	// Timestamp of the quoted message, if any.
+	QuoteTimestamp int64 `json:"quote_timestamp,omitempty"`
+	// Previous versions of the message, oldest first.
+	Edits []MessageEdit `json:"edits,omitempty"`
+	// Whether the message was deleted by its sender.
+	Deleted   bool             `json:"deleted,omitempty"`
+	Reactions []StoredReaction `json:"reactions,omitempty"`
+}
+
+// Returns the time the message was sent.
+func (s StoredMessage) Time() time.Time {
+	return time.UnixMilli(s.Timestamp)
+}
+
+// Reports whether the message was sent by author, a number or UUID.
+func (s StoredMessage) SentBy(author string) bool {
+	return author != "" && (author == s.SenderNumber || author == s.SenderUUID)
+}
+
+// A previous version of an edited message.
+type MessageEdit struct {
+	// Timestamp the version was replaced at.
+	Timestamp int64 `json:"timestamp"`
+	// Text of the previous version.
+	Text string `json:"text"`
+}
+
+// A reaction to a stored message. Each author has at most one reaction per message.
+type StoredReaction struct {
+	// Number or UUID of the user who reacted.
+	Author    string `json:"author"`
+	Emoji     string `json:"emoji"`
+	Timestamp int64  `json:"timestamp"`
+}
+
+// Filters for MessageStore.Query. Empty fields match everything.
+type MessageQuery struct {
+	// Number of the account that sent or received the messages.
+	Account string
+	// Contact number or UUID, or group in `group.` form or as found in received messages.
+	Conversation string
+	// Number or UUID of the sender.
+	Sender string
+	// Only messages sent at or after Since.
+	Since time.Time
+	// Only messages sent before Until.
+	Until time.Time
+	// Only messages whose text contains every word of Text, ignoring case.
+	Text string
+	// Also return deleted messages.
+	IncludeDeleted bool
+	// Return at most the Limit most recent matches. Zero means no limit.
+	Limit int
+}
+
+func (q MessageQuery) matches(s *StoredMessage) bool {
+	if q.Account != "" && s.Account != q.Account {
+		return false
+	}
+	if q.Conversation != "" && s.Conversation != q.Conversation && s.Conversation != groupRecipient(q.Conversation) {
+		return false
+	}
+	if q.Sender != "" && !s.SentBy(q.Sender) {
+		return false
+	}
+	if !q.Since.IsZero() && s.Time().Before(q.Since) {
+		return false
+	}
+	if !q.Until.IsZero() && !s.Time().Before(q.Until) {
+		return false
+	}
+	if s.Deleted && !q.IncludeDeleted {
+		return false
+	}
+	if q.Text != "" {
+		text := strings.ToLower(s.Text)
+		for _, word := range strings.Fields(strings.ToLower(q.Text)) {
+			if !strings.Contains(text, word) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// A MessageStore keeps a queryable history of received and sent messages.
+type MessageStore interface {
+	// Stores a received message, or applies it to the stored message it edits, deletes or reacts to.
+	// Messages of other kinds are ignored.
+	Record(ctx context.Context, m MessageResponse) error
+	// Stores a message sent with PostSend, once per recipient.
+	RecordSent(ctx context.Context, msg SendMessageV2, result SendResult) error
+	// Returns the message of account sent by author, a number or UUID, at timestamp. Returns an error matching ErrNotFound if there is none.
+	Get(ctx context.Context, account, author string, timestamp int64) (StoredMessage, error)
+	// Returns the messages matching q, oldest first.
+	Query(ctx context.Context, q MessageQuery) ([]StoredMessage, error)
+}
+
+// Returns middleware that records every message passing through a Router in store before handling it.
+func RecordMiddleware(store MessageStore) Middleware {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, m MessageResponse) error {
+			return errors.Join(store.Record(ctx, m), next(ctx, m))
+		}
+	}
+}
+
+// A MemoryMessageStore keeps messages in memory.
+type MemoryMessageStore struct {
+	mu       sync.RWMutex
+	messages []*StoredMessage
+	// Messages by account and timestamp, several senders may share a timestamp.
+	index map[storeKey][]*StoredMessage
+}
+
+type storeKey struct {
+	account   string
+	timestamp int64
+}
+
+// Creates an empty MemoryMessageStore.
+func NewMemoryMessageStore() *MemoryMessageStore {
+	return &MemoryMessageStore{}
+}
+
+func (s *MemoryMessageStore) Record(ctx context.Context, m MessageResponse) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.record(m)
+	return nil
+}
+
+func (s *MemoryMessageStore) record(m MessageResponse) {
+	e := m.Envelope
+	switch m.Kind() {
+	case EventText:
+		s.add(&StoredMessage{
+			Account:        m.Account,
+			Conversation:   m.Conversation(),
+			SenderNumber:   e.SourceNumber,
+			SenderUUID:     e.SourceUuid,
+			SenderName:     e.SourceName,
+			Timestamp:      m.Timestamp(),
+			Text:           e.DataMessage.Message,
+			Attachments:    e.DataMessage.Attachments,
+			QuoteTimestamp: e.DataMessage.Quote.Id,
+		})
+	case EventEdit:
+		s.edit(m.Account, m.Sender(), e.EditMessage, e.Timestamp)
+	case EventDelete:
+		s.remoteDelete(m.Account, m.Sender(), e.DataMessage.RemoteDelete.Timestamp)
+	case EventReaction:
+		s.react(m.Account, m.Sender(), e.DataMessage.Reaction, e.Timestamp)
+	case EventSyncSent:
+		sent := e.SyncMessage.SentMessage
+		switch {
+		case sent.EditMessage.TargetSentTimestamp != 0:
+			s.edit(m.Account, m.Account, sent.EditMessage, sent.Timestamp)
+		case sent.RemoteDelete.Timestamp != 0:
+			s.remoteDelete(m.Account, m.Account, sent.RemoteDelete.Timestamp)
+		case sent.Reaction.Emoji != "":
+			s.react(m.Account, m.Account, sent.Reaction, sent.Timestamp)
+		default:
+			conversation := sent.DestinationNumber
+			if sent.GroupInfo.GroupId != "" {
+				conversation = groupRecipient(sent.GroupInfo.GroupId)
+			} else if conversation == "" {
+				conversation = cmp.Or(sent.DestinationUuid, sent.Destination)
+			}
+			s.add(&StoredMessage{
+				Account:        m.Account,
+				Conversation:   conversation,
+				SenderNumber:   m.Account,
+				SenderUUID:     e.SourceUuid,
+				Timestamp:      sent.Timestamp,
+				Outgoing:       true,
+				Text:           sent.Message,
+				Attachments:    sent.Attachments,
+				QuoteTimestamp: sent.Quote.Id,
+			})
+		}
+	}
+}
+
+func (s *MemoryMessageStore) RecordSent(ctx context.Context, msg SendMessageV2, result SendResult) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.recordSent(msg, result.Timestamp)
+	return nil
+}
+
+func (s *MemoryMessageStore) recordSent(msg SendMessageV2, timestamp int64) {
+	if msg.EditTimestamp != nil {
+		edit := signaltypes.EditMessage{TargetSentTimestamp: *msg.EditTimestamp}
+		edit.DataMessage.Message = msg.Message
+		s.edit(msg.Number, msg.Number, edit, timestamp)
+		return
+	}
+	for _, recipient := range msg.Recipients {
+		stored := &StoredMessage{
+			Account:      msg.Number,
+			Conversation: recipient,
+			SenderNumber: msg.Number,
+			Timestamp:    timestamp,
+			Outgoing:     true,
+			Text:         msg.Message,
+		}
+		if msg.QuoteTimestamp != nil {
+			stored.QuoteTimestamp = *msg.QuoteTimestamp
+		}
+		s.add(stored)
+	}
+}
+
+// Adds stored, unless the same message was already stored, e.g. when a sync message arrives for a message recorded with RecordSent.
+func (s *MemoryMessageStore) add(stored *StoredMessage) {
+	if s.index == nil {
+		s.index = map[storeKey][]*StoredMessage{}
+	}
+	key := storeKey{stored.Account, stored.Timestamp}
+	for _, existing := range s.index[key] {
+		if existing.Conversation == stored.Conversation &&
+			(existing.SentBy(stored.SenderNumber) || existing.SentBy(stored.SenderUUID)) {
+			return
+		}
+	}
+	s.index[key] = append(s.index[key], stored)
+	// Messages mostly arrive in order, so inserting from the back is cheap.
+	i := len(s.messages)
+	for i > 0 && s.messages[i-1].Timestamp > stored.Timestamp {
+		i--
+	}
+	s.messages = slices.Insert(s.messages, i, stored)
+}
+
+// Returns the messages of account sent by author at timestamp, one per conversation for messages we sent to several recipients.
+func (s *MemoryMessageStore) find(account, author string, timestamp int64) (found []*StoredMessage) {
+	for _, stored := range s.index[storeKey{account, timestamp}] {
+		if stored.SentBy(author) {
+			found = append(found, stored)
+		}
+	}
+	return
+}
+
+func (s *MemoryMessageStore) edit(account, author string, edit signaltypes.EditMessage, timestamp int64) {
+	for _, stored := range s.find(account, author, edit.TargetSentTimestamp) {
+		stored.Edits = append(stored.Edits, MessageEdit{Timestamp: timestamp, Text: stored.Text})
+		stored.Text = edit.DataMessage.Message
+		if len(edit.DataMessage.Attachments) > 0 {
+			stored.Attachments = edit.DataMessage.Attachments
+		}
+	}
+}
+
+func (s *MemoryMessageStore) remoteDelete(account, author string, timestamp int64) {
+	for _, stored := range s.find(account, author, timestamp) {
+		stored.Deleted = true
+		stored.Text = ""
+		stored.Attachments = nil
+		stored.Edits = nil
+	}
+}
+
+func (s *MemoryMessageStore) react(account, author string, reaction signaltypes.Reaction, timestamp int64) {
+	targets := []string{reaction.TargetAuthorNumber, reaction.TargetAuthorUuid, reaction.TargetAuthor}
+	for _, target := range targets {
+		found := s.find(account, target, reaction.TargetSentTimestamp)
+		if len(found) == 0 {
+			continue
+		}
+		for _, stored := range found {
+			stored.Reactions = slices.DeleteFunc(stored.Reactions, func(r StoredReaction) bool { return r.Author == author })
+			if !reaction.IsRemove {
+				stored.Reactions = append(stored.Reactions, StoredReaction{Author: author, Emoji: reaction.Emoji, Timestamp: timestamp})
+			}
+		}
+		return
+	}
+}
+
+func (s *MemoryMessageStore) Get(ctx context.Context, account, author string, timestamp int64) (StoredMessage, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	found := s.find(account, author, timestamp)
+	if len(found) == 0 {
+		return StoredMessage{}, fmt.Errorf("message %d from %s: %w", timestamp, author, ErrNotFound)
+	}
+	return found[0].clone(), nil
+}
+
+func (s *MemoryMessageStore) Query(ctx context.Context, q MessageQuery) (messages []StoredMessage, err error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	// Walk backwards so a Limit keeps the most recent messages.
+	for i := len(s.messages) - 1; i >= 0; i-- {
+		if q.Limit > 0 && len(messages) >= q.Limit {
+			break
+		}
+		if q.matches(s.messages[i]) {
+			messages = append(messages, s.messages[i].clone())
+		}
+	}
+	slices.Reverse(messages)
+	return
+}
+
+func (s *StoredMessage) clone() StoredMessage {
+	c := *s
+	c.Attachments = slices.Clone(s.Attachments)
+	c.Edits = slices.Clone(s.Edits)
+	c.Reactions = slices.Clone(s.Reactions)
+	return c
+}
+
+// A FileMessageStore keeps messages in memory and appends everything it records to a JSON lines file, which is replayed when the store is opened.
+type FileMessageStore struct {
+	memory *MemoryMessageStore
+	mu     sync.Mutex
+	file   *os.File
+}
+
+// One line of a FileMessageStore's file.
+type fileStoreEntry struct {
+	Received *MessageResponse `json:"received,omitempty"`
+	Sent     *SendMessageV2   `json:"sent,omitempty"`
+	// Timestamp of the sent message.
+	Timestamp int64 `json:"timestamp,omitempty"`
+}
+
+// Opens the FileMessageStore at path, creating the file if it does not exist.
+func OpenFileMessageStore(path string) (*FileMessageStore, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	s := &FileMessageStore{memory: NewMemoryMessageStore(), file: file}
+	if err := s.replay(path); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// Replays the entries of the store's file into memory.
+//
+// A last line without a newline was cut short by a crash while it was written. It is replayed if it is complete and truncated otherwise, so the store opens again.
+func (s *FileMessageStore) replay(path string) error {
+	reader := bufio.NewReader(s.file)
+	var offset int64
+	for line := 1; ; line++ {
+		data, err := reader.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		if len(data) == 0 {
+			return nil
+		}
+		var entry fileStoreEntry
+		if jsonErr := json.Unmarshal(data, &entry); jsonErr != nil {
+			if err == io.EOF {
+				if err := s.file.Truncate(offset); err != nil {
+					return fmt.Errorf("failed to truncate the partial last line of %s: %w", path, err)
+				}
+				return nil
+			}
+			return fmt.Errorf("failed to read %s line %d: %w", path, line, jsonErr)
+		}
+		switch {
+		case entry.Received != nil:
+			s.memory.record(*entry.Received)
+		case entry.Sent != nil:
+			s.memory.recordSent(*entry.Sent, entry.Timestamp)
+		}
+		if err == io.EOF {
+			// Complete the line, so the next entry starts on its own.
+			if _, err := s.file.Write([]byte{'\n'}); err != nil {
+				return fmt.Errorf("failed to repair %s: %w", path, err)
+			}
+			return nil
+		}
+		offset += int64(len(data))
+	}
+}
+
+func (s *FileMessageStore) append(entry fileStoreEntry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	_, err = s.file.Write(append(line, '\n'))
+	return err
+}
+
+func (s *FileMessageStore) Record(ctx context.Context, m MessageResponse) error {
+	switch m.Kind() {
+	case EventText, EventEdit, EventDelete, EventReaction, EventSyncSent:
+	default:
+		return nil
+	}
+	// The raw fields duplicate the envelope.
+	m.RawFields = nil
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.append(fileStoreEntry{Received: &m}); err != nil {
+		return fmt.Errorf("failed to record message: %w", err)
+	}
+	return s.memory.Record(ctx, m)
+}
+
+func (s *FileMessageStore) RecordSent(ctx context.Context, msg SendMessageV2, result SendResult) error {
+	timestamp := result.Timestamp
+	// Attachments are not kept, only their absence would be noticed.
+	msg.Base64Attachments = nil
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.append(fileStoreEntry{Sent: &msg, Timestamp: timestamp}); err != nil {
+		return fmt.Errorf("failed to record sent message: %w", err)
+	}
+	s.memory.mu.Lock()
+	defer s.memory.mu.Unlock()
+	s.memory.recordSent(msg, timestamp)
+	return nil
+}
+
+func (s *FileMessageStore) Get(ctx context.Context, account, author string, timestamp int64) (StoredMessage, error) {
+	return s.memory.Get(ctx, account, author, timestamp)
+}
+
+func (s *FileMessageStore) Query(ctx context.Context, q MessageQuery) ([]StoredMessage, error) {
+	return s.memory.Query(ctx, q)
+}
+
+// Closes the store's file.
+func (s *FileMessageStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.file.Close()
+}
+
+var _ MessageStore = (*MemoryMessageStore)(nil)
+var _ MessageStore = (*FileMessageStore)(nil)
@@ -0,0 +1,190 @@
+package signalmgr_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/DonovanDiamond/signalmgr"
+	"github.com/DonovanDiamond/signalmgr/signalmgrtest"
+	"github.com/DonovanDiamond/signalmgr/signaltypes"
+)
+
+func received(e signaltypes.MessageEnvelope, timestamp int64) signalmgr.MessageResponse {
+	e.Timestamp = timestamp
+	if e.DataMessage.Message != "" {
+		e.DataMessage.Timestamp = timestamp
+	}
+	return signalmgr.MessageResponse{Envelope: e, Account: testNumber}
+}
+
+func TestFileMessageStoreReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "messages.jsonl")
+	ctx := context.Background()
+	store, err := signalmgr.OpenFileMessageStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range []signalmgr.MessageResponse{
+		received(signalmgrtest.TextEnvelope(otherNumber, "first"), 1000),
+		received(signalmgrtest.TextEnvelope(otherNumber, "second"), 2000),
+		received(signalmgrtest.EditEnvelope(otherNumber, 1000, "first, edited"), 3000),
+		received(signalmgrtest.DeleteEnvelope(otherNumber, 2000), 4000),
+	} {
+		if err := store.Record(ctx, m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	sent := signalmgr.SendMessageV2{Number: testNumber, Recipients: []string{otherNumber}, Message: "reply"}
+	if err := store.RecordSent(ctx, sent, signalmgr.SendResult{Timestamp: 5000}); err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range []signalmgr.MessageResponse{
+		received(signalmgrtest.ReactionEnvelope(otherNumber, "👍", testNumber, 5000), 6000),
+		// Receipts are not stored.
+		received(signalmgrtest.ReceiptEnvelope(otherNumber, "read", 5000), 7000),
+	} {
+		if err := store.Record(ctx, m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := store.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	store, err = signalmgr.OpenFileMessageStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	first, err := store.Get(ctx, testNumber, otherNumber, 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.Text != "first, edited" || len(first.Edits) != 1 || first.Edits[0].Text != "first" {
+		t.Errorf("edited message = %+v", first)
+	}
+	reply, err := store.Get(ctx, testNumber, testNumber, 5000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reply.Outgoing || reply.Text != "reply" || reply.Conversation != otherNumber {
+		t.Errorf("sent message = %+v", reply)
+	}
+	if len(reply.Reactions) != 1 || reply.Reactions[0] != (signalmgr.StoredReaction{Author: otherNumber, Emoji: "👍", Timestamp: 6000}) {
+		t.Errorf("got reactions %+v", reply.Reactions)
+	}
+	if _, err := store.Get(ctx, testNumber, otherNumber, 9999); !errors.Is(err, signalmgr.ErrNotFound) {
+		t.Errorf("missing message: got %v, want ErrNotFound", err)
+	}
+
+	tests := []struct {
+		name  string
+		query signalmgr.MessageQuery
+		want  []string
+	}{
+		{"all", signalmgr.MessageQuery{}, []string{"first, edited", "reply"}},
+		{"deleted", signalmgr.MessageQuery{IncludeDeleted: true}, []string{"first, edited", "", "reply"}},
+		{"sender", signalmgr.MessageQuery{Sender: otherNumber}, []string{"first, edited"}},
+		{"text", signalmgr.MessageQuery{Text: "EDITED first"}, []string{"first, edited"}},
+		{"limit", signalmgr.MessageQuery{Limit: 1, Conversation: otherNumber}, []string{"reply"}},
+		{"other account", signalmgr.MessageQuery{Account: otherNumber}, nil},
+	}
+	for _, tt := range tests {
+		messages, err := store.Query(ctx, tt.query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, m := range messages {
+			got = append(got, m.Text)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestFileMessageStorePartialLine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "messages.jsonl")
+	ctx := context.Background()
+	record := func(text string, timestamp int64) {
+		t.Helper()
+		store, err := signalmgr.OpenFileMessageStore(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer store.Close()
+		if err := store.Record(ctx, received(signalmgrtest.TextEnvelope(otherNumber, text), timestamp)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	appendFile := func(data string) {
+		t.Helper()
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		if _, err := f.WriteString(data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	texts := func() []string {
+		t.Helper()
+		store, err := signalmgr.OpenFileMessageStore(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer store.Close()
+		messages, _ := store.Query(ctx, signalmgr.MessageQuery{})
+		var texts []string
+		for _, m := range messages {
+			texts = append(texts, m.Text)
+		}
+		return texts
+	}
+
+	record("first", 1000)
+	// A crash while writing leaves a partial line, which is dropped.
+	appendFile(`{"received":{"envelope":{"sourceNum`)
+	if got := texts(); !slices.Equal(got, []string{"first"}) {
+		t.Errorf("after a partial line: got %q", got)
+	}
+	record("second", 2000)
+	if got := texts(); !slices.Equal(got, []string{"first", "second"}) {
+		t.Errorf("after recording again: got %q", got)
+	}
+
+	// A complete line that only lacks its newline is kept.
+	other := filepath.Join(t.TempDir(), "other.jsonl")
+	store, err := signalmgr.OpenFileMessageStore(other)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.Record(ctx, received(signalmgrtest.TextEnvelope(otherNumber, "third"), 3000))
+	store.Close()
+	line, err := os.ReadFile(other)
+	if err != nil {
+		t.Fatal(err)
+	}
+	appendFile(strings.TrimSuffix(string(line), "\n"))
+	if got := texts(); !slices.Equal(got, []string{"first", "second", "third"}) {
+		t.Errorf("after a line without newline: got %q", got)
+	}
+	record("fourth", 4000)
+	if got := texts(); len(got) != 4 || got[3] != "fourth" {
+		t.Errorf("after recording again: got %q", got)
+	}
+
+	// Broken lines before the last one are still an error.
+	appendFile("not json\n{}\n")
+	if _, err := signalmgr.OpenFileMessageStore(path); err == nil || !strings.Contains(err.Error(), "line 5") {
+		t.Errorf("got %v, want an error for line 5", err)
+	}
+}
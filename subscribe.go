@@ -0,0 +1,144 @@
+package signalmgr
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// State of the websocket behind a subscription.
+type ConnState int
+
+const (
+	// The socket is open and messages are being received.
+	ConnConnected ConnState = iota
+	// The socket failed or could not be opened and a new connection will be attempted after a backoff.
+	ConnReconnecting
+	// The subscription stopped and will not reconnect.
+	ConnClosed
+)
+
+func (s ConnState) String() string {
+	switch s {
+	case ConnConnected:
+		return "connected"
+	case ConnReconnecting:
+		return "reconnecting"
+	case ConnClosed:
+		return "closed"
+	}
+	return fmt.Sprintf("ConnState(%d)", int(s))
+}
+
+// Options for Subscribe. The zero value uses sensible defaults.
+type SubscribeOptions struct {
+	// Called whenever the connection state changes, with the error that caused the change, if any.
+	OnStateChange func(state ConnState, err error)
+	// Backoff between reconnect attempts. MaxAttempts limits consecutive failed attempts, zero retries forever.
+	// Defaults to DefaultReconnectPolicy.
+	Reconnect *RetryPolicy
+	// Interval between keepalive pings. Defaults to 30 seconds.
+	PingInterval time.Duration
+	// How long to wait for a pong before the connection is considered dead. Defaults to twice the PingInterval.
+	PongTimeout time.Duration
+}
+
+// DefaultReconnectPolicy is used by Subscribe when no Reconnect policy is set.
+var DefaultReconnectPolicy = RetryPolicy{
+	InitialBackoff: time.Second,
+	MaxBackoff:     time.Minute,
+	Multiplier:     2,
+	Jitter:         0.2,
+}
+
+func (o *SubscribeOptions) reconnectPolicy() *RetryPolicy {
+	if o.Reconnect == nil {
+		return &DefaultReconnectPolicy
+	}
+	return o.Reconnect
+}
+
+func (o *SubscribeOptions) setState(state ConnState, err error) {
+	if o.OnStateChange != nil {
+		o.OnStateChange(state, err)
+	}
+}
+
+// Opens a socket to receive Signal Messages and sends them to the `messages` channel, reconnecting whenever the socket fails.
+//
+// Keepalive pings are sent to detect dead connections. Will only return once ctx is done, returning ctx.Err(), or once opts.Reconnect.MaxAttempts consecutive connection attempts have failed.
+//
+// Only works if the signal api is running in `json-rpc` mode.
+func (a *Account) Subscribe(ctx context.Context, messages chan<- MessageResponse, opts *SubscribeOptions) error {
+	if opts == nil {
+		opts = &SubscribeOptions{}
+	}
+	reconnect := opts.reconnectPolicy()
+	pingInterval := opts.PingInterval
+	if pingInterval <= 0 {
+		pingInterval = 30 * time.Second
+	}
+	pongTimeout := opts.PongTimeout
+	if pongTimeout <= 0 {
+		pongTimeout = 2 * pingInterval
+	}
+
+	failures := 0
+	for {
+		c, err := a.client().dial(ctx, fmt.Sprintf("/v1/receive/%s", a.Number))
+		if err == nil {
+			failures = 0
+			opts.setState(ConnConnected, nil)
+			err = readSocketKeepalive(ctx, a.client(), c, messages, pingInterval, pongTimeout)
+		} else {
+			err = fmt.Errorf("failed to dial websocket: %w", err)
+		}
+		if ctx.Err() != nil {
+			opts.setState(ConnClosed, nil)
+			return ctx.Err()
+		}
+
+		failures++
+		if reconnect.MaxAttempts > 0 && failures >= reconnect.MaxAttempts {
+			opts.setState(ConnClosed, err)
+			return err
+		}
+		opts.setState(ConnReconnecting, err)
+
+		timer := time.NewTimer(reconnect.backoff(failures))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			opts.setState(ConnClosed, nil)
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// Same as readSocket, but pings the other side every pingInterval and fails if neither a message nor a pong arrives within pongTimeout.
+func readSocketKeepalive(ctx context.Context, client *Client, c Conn, messages chan<- MessageResponse, pingInterval, pongTimeout time.Duration) error {
+	c.SetPongHandler(func(string) error {
+		return c.SetReadDeadline(time.Now().Add(pongTimeout))
+	})
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		ticker := time.NewTicker(pingInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				// A failed ping surfaces as a read error once the pong timeout passes.
+				c.WriteControl(websocket.PingMessage, nil, time.Now().Add(pingInterval))
+			}
+		}
+	}()
+
+	return readSocket(ctx, client, c, messages, pongTimeout)
+}
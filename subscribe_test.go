@@ -0,0 +1,169 @@
+package signalmgr_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DonovanDiamond/signalmgr"
+	"github.com/DonovanDiamond/signalmgr/signalmgrtest"
+	"github.com/gorilla/websocket"
+)
+
+// A subscription running until the test ends, reporting its state changes.
+type subscription struct {
+	messages chan signalmgr.MessageResponse
+	states   chan signalmgr.ConnState
+	done     chan error
+}
+
+// Runs run with opts until the test ends, recording the state changes reported to opts.
+func startSubscription(t *testing.T, opts *signalmgr.SubscribeOptions, run func(ctx context.Context, messages chan<- signalmgr.MessageResponse) error) *subscription {
+	t.Helper()
+	s := &subscription{
+		messages: make(chan signalmgr.MessageResponse),
+		states:   make(chan signalmgr.ConnState, 64),
+		done:     make(chan error, 1),
+	}
+	opts.OnStateChange = func(state signalmgr.ConnState, err error) {
+		s.states <- state
+	}
+	if opts.Reconnect == nil {
+		opts.Reconnect = fastRetry()
+		opts.Reconnect.MaxAttempts = 0
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		s.done <- run(ctx, s.messages)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-s.done
+	})
+	return s
+}
+
+func subscribe(t *testing.T, account *signalmgr.Account, opts *signalmgr.SubscribeOptions) *subscription {
+	t.Helper()
+	return startSubscription(t, opts, func(ctx context.Context, messages chan<- signalmgr.MessageResponse) error {
+		return account.Subscribe(ctx, messages, opts)
+	})
+}
+
+func (s *subscription) waitState(t *testing.T, want signalmgr.ConnState) {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case state := <-s.states:
+			if state == want {
+				return
+			}
+		case <-timeout:
+			t.Fatalf("subscription never got %v", want)
+		}
+	}
+}
+
+func (s *subscription) receive(t *testing.T) signalmgr.MessageResponse {
+	t.Helper()
+	select {
+	case m := <-s.messages:
+		return m
+	case <-time.After(5 * time.Second):
+		t.Fatal("no message received")
+		return signalmgr.MessageResponse{}
+	}
+}
+
+func TestSubscribeReconnects(t *testing.T) {
+	srv := signalmgrtest.NewServer(testNumber)
+	defer srv.Close()
+	s := subscribe(t, srv.Account(testNumber), &signalmgr.SubscribeOptions{})
+
+	s.waitState(t, signalmgr.ConnConnected)
+	srv.Deliver(testNumber, signalmgrtest.TextEnvelope(otherNumber, "before"))
+	if m := s.receive(t); m.Envelope.DataMessage.Message != "before" {
+		t.Errorf("got %q", m.Envelope.DataMessage.Message)
+	}
+
+	srv.DropConnections()
+	s.waitState(t, signalmgr.ConnReconnecting)
+	s.waitState(t, signalmgr.ConnConnected)
+	srv.Deliver(testNumber, signalmgrtest.TextEnvelope(otherNumber, "after"))
+	if m := s.receive(t); m.Envelope.DataMessage.Message != "after" {
+		t.Errorf("got %q", m.Envelope.DataMessage.Message)
+	}
+	if n := len(srv.RequestsTo(http.MethodGet, "/v1/receive/"+testNumber)); n != 2 {
+		t.Errorf("got %d dials, want 2", n)
+	}
+}
+
+func TestSubscribeGivesUp(t *testing.T) {
+	srv := signalmgrtest.NewServer(testNumber)
+	defer srv.Close()
+	reconnect := fastRetry()
+	reconnect.MaxAttempts = 3
+	var states []signalmgr.ConnState
+	// otherNumber is not registered, so every dial fails.
+	err := srv.Account(otherNumber).Subscribe(context.Background(), nil, &signalmgr.SubscribeOptions{
+		Reconnect: reconnect,
+		OnStateChange: func(state signalmgr.ConnState, err error) {
+			states = append(states, state)
+		},
+	})
+	if !errors.Is(err, signalmgr.ErrAccountNotRegistered) {
+		t.Errorf("got %v, want ErrAccountNotRegistered", err)
+	}
+	want := []signalmgr.ConnState{signalmgr.ConnReconnecting, signalmgr.ConnReconnecting, signalmgr.ConnClosed}
+	if len(states) != len(want) {
+		t.Fatalf("got states %v, want %v", states, want)
+	}
+	for i := range want {
+		if states[i] != want[i] {
+			t.Errorf("got states %v, want %v", states, want)
+		}
+	}
+}
+
+func TestSubscribeKeepalive(t *testing.T) {
+	opts := func() *signalmgr.SubscribeOptions {
+		return &signalmgr.SubscribeOptions{PingInterval: 10 * time.Millisecond, PongTimeout: 50 * time.Millisecond}
+	}
+
+	// The fake answers pings, so a quiet connection stays open.
+	srv := signalmgrtest.NewServer(testNumber)
+	defer srv.Close()
+	s := subscribe(t, srv.Account(testNumber), opts())
+	s.waitState(t, signalmgr.ConnConnected)
+	select {
+	case state := <-s.states:
+		t.Errorf("answered pings: got state %v", state)
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	// A server that swallows pings is considered dead once the pong timeout passes.
+	upgrader := websocket.Upgrader{}
+	silent := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ws, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer ws.Close()
+		ws.SetPingHandler(func(string) error { return nil })
+		for {
+			if _, _, err := ws.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}))
+	defer silent.Close()
+	account := &signalmgr.Account{Number: testNumber, Client: &signalmgr.Client{URL: silent.URL}}
+	s = subscribe(t, account, opts())
+	s.waitState(t, signalmgr.ConnConnected)
+	s.waitState(t, signalmgr.ConnReconnecting)
+	s.waitState(t, signalmgr.ConnConnected)
+}
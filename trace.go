@@ -0,0 +1,77 @@
+package signalmgr
+
+import (
+	"context"
+	"fmt"
+)
+
+// A Tracer starts spans, e.g. an adapter for an OpenTelemetry tracer.
+//
+// Set it as Client.Tracer to get a span for every request and every message read from a receive websocket, and one for every Router.Dispatch of those messages.
+type Tracer interface {
+	// Starts a span named name as a child of the span in ctx, if any, and returns ctx with the new span.
+	Start(ctx context.Context, name string) (context.Context, Span)
+}
+
+// A Span is an operation being traced.
+type Span interface {
+	// Sets an attribute, e.g. "http.response.status_code" to an int.
+	SetAttribute(key string, value any)
+	// Records err as the reason the operation failed.
+	RecordError(err error)
+	// Ends the span.
+	End()
+}
+
+// Starts a span for req named after its method and route.
+func (c *Client) startRequestSpan(ctx context.Context, req *Request) (context.Context, Span) {
+	ctx, span := c.Tracer.Start(ctx, req.Method+" "+req.Route)
+	span.SetAttribute("http.request.method", req.Method)
+	span.SetAttribute("http.route", req.Route)
+	span.SetAttribute("url.path", DefaultRedactor.text(req.Path))
+	return ctx, span
+}
+
+// Records the outcome of a request in span and ends it.
+func endRequestSpan(span Span, resp *Response, err error) {
+	if err != nil {
+		span.RecordError(err)
+	} else {
+		span.SetAttribute("http.response.status_code", resp.StatusCode)
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			span.RecordError(fmt.Errorf("status %d", resp.StatusCode))
+		}
+	}
+	span.End()
+}
+
+// Sets the attributes describing m on span.
+func setMessageAttributes(span Span, m MessageResponse) {
+	span.SetAttribute("signal.account", DefaultRedactor.text(m.Account))
+	span.SetAttribute("signal.sender", DefaultRedactor.text(m.Sender()))
+	span.SetAttribute("signal.kind", m.Kind().String())
+	span.SetAttribute("signal.timestamp", m.Timestamp())
+}
+
+// Returns ctx carrying the span m was received in, so spans started from it continue the message's trace.
+//
+// Returns ctx as is if m was not traced.
+func (m MessageResponse) TraceContext(ctx context.Context) context.Context {
+	if m.traceCtx == nil {
+		return ctx
+	}
+	return tracedContext{ctx, m.traceCtx}
+}
+
+// A context with the deadline and cancellation of its Context, and the values of traced before those of its Context.
+type tracedContext struct {
+	context.Context
+	traced context.Context
+}
+
+func (c tracedContext) Value(key any) any {
+	if v := c.traced.Value(key); v != nil {
+		return v
+	}
+	return c.Context.Value(key)
+}
@@ -0,0 +1,168 @@
+package signalmgr_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/DonovanDiamond/signalmgr"
+	"github.com/DonovanDiamond/signalmgr/signalmgrtest"
+	"github.com/DonovanDiamond/signalmgr/signaltypes"
+)
+
+// A Tracer keeping its spans in memory.
+type testTracer struct {
+	mu    sync.Mutex
+	spans []*testSpan
+}
+
+type testSpan struct {
+	tracer *testTracer
+	name   string
+	parent *testSpan
+	attrs  map[string]any
+	errs   []error
+	ended  bool
+}
+
+type spanKey struct{}
+
+func (t *testTracer) Start(ctx context.Context, name string) (context.Context, signalmgr.Span) {
+	parent, _ := ctx.Value(spanKey{}).(*testSpan)
+	span := &testSpan{tracer: t, name: name, parent: parent, attrs: map[string]any{}}
+	t.mu.Lock()
+	t.spans = append(t.spans, span)
+	t.mu.Unlock()
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+// Returns the spans named name.
+func (t *testTracer) named(name string) []*testSpan {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var spans []*testSpan
+	for _, span := range t.spans {
+		if span.name == name {
+			spans = append(spans, span)
+		}
+	}
+	return spans
+}
+
+func (s *testSpan) SetAttribute(key string, value any) {
+	s.tracer.mu.Lock()
+	defer s.tracer.mu.Unlock()
+	s.attrs[key] = value
+}
+
+func (s *testSpan) RecordError(err error) {
+	s.tracer.mu.Lock()
+	defer s.tracer.mu.Unlock()
+	s.errs = append(s.errs, err)
+}
+
+func (s *testSpan) End() {
+	s.tracer.mu.Lock()
+	defer s.tracer.mu.Unlock()
+	s.ended = true
+}
+
+func TestTraceRequests(t *testing.T) {
+	srv := signalmgrtest.NewServer(testNumber)
+	defer srv.Close()
+	tracer := &testTracer{}
+	client := srv.Client()
+	client.Retry = &signalmgr.RetryPolicy{}
+	client.Tracer = tracer
+	ctx := context.Background()
+
+	if _, err := client.GetSearchCtx(ctx, []string{otherNumber}); err != nil {
+		t.Fatal(err)
+	}
+	srv.FailNext(http.MethodGet, "/v1/groups/"+testNumber, http.StatusBadRequest, "failed")
+	if _, err := client.Account(testNumber).GetGroupsCtx(ctx); err == nil {
+		t.Fatal("the failed request succeeded")
+	}
+
+	search := tracer.named("GET /v1/search")
+	if len(search) != 1 {
+		t.Fatalf("got %d search spans, want 1", len(search))
+	}
+	span := search[0]
+	path, _ := span.attrs["url.path"].(string)
+	if !strings.HasPrefix(path, "/v1/search?numbers=%2B1555") || strings.Contains(path, "4155550199") {
+		t.Errorf("url.path is %q, want the number redacted", path)
+	}
+	if span.attrs["http.request.method"] != "GET" || span.attrs["http.route"] != "/v1/search" ||
+		span.attrs["http.response.status_code"] != 200 || len(span.errs) != 0 || !span.ended {
+		t.Errorf("got span %+v", span)
+	}
+
+	groups := tracer.named("GET /v1/groups/{number}")
+	if len(groups) != 1 {
+		t.Fatalf("got %d groups spans, want 1", len(groups))
+	}
+	span = groups[0]
+	if path := span.attrs["url.path"]; path == "/v1/groups/"+testNumber {
+		t.Errorf("url.path is %q, want the number redacted", path)
+	}
+	if span.attrs["http.response.status_code"] != 400 || len(span.errs) != 1 || !span.ended {
+		t.Errorf("got span %+v", span)
+	}
+}
+
+func TestTraceReceive(t *testing.T) {
+	srv := signalmgrtest.NewServer(testNumber)
+	defer srv.Close()
+	tracer := &testTracer{}
+	client := srv.Client()
+	client.Tracer = tracer
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	messages := make(chan signalmgr.MessageResponse)
+	go client.Account(testNumber).GetMessagesSocketCtx(ctx, messages)
+	for srv.Connections(testNumber) == 0 {
+		time.Sleep(5 * time.Millisecond)
+	}
+	srv.Deliver(testNumber, signalmgrtest.TextEnvelope(otherNumber, "hi"))
+	var m signalmgr.MessageResponse
+	select {
+	case m = <-messages:
+	case <-ctx.Done():
+		t.Fatal("no message received")
+	}
+
+	router := signalmgr.NewRouter()
+	router.OnText(func(ctx context.Context, m signalmgr.MessageResponse, msg signaltypes.DataMessage) error {
+		return errors.New("not handled")
+	})
+	if err := router.Dispatch(context.Background(), m); err == nil {
+		t.Fatal("the handler's error was not returned")
+	}
+
+	received := tracer.named("signal receive")
+	dispatched := tracer.named("signal dispatch")
+	if len(received) != 1 || len(dispatched) != 1 {
+		t.Fatalf("got %d receive and %d dispatch spans, want 1 each", len(received), len(dispatched))
+	}
+	// Dispatching continues the trace of the message, not that of its ctx.
+	if dispatched[0].parent != received[0] {
+		t.Error("the dispatch span is not a child of the receive span")
+	}
+	for _, span := range []*testSpan{received[0], dispatched[0]} {
+		account, _ := span.attrs["signal.account"].(string)
+		sender, _ := span.attrs["signal.sender"].(string)
+		if !strings.HasPrefix(account, "+1555") || !strings.HasPrefix(sender, "+1555") ||
+			span.attrs["signal.kind"] != "text" || span.attrs["signal.timestamp"] != m.Timestamp() {
+			t.Errorf("%s: got attributes %v", span.name, span.attrs)
+		}
+	}
+	if len(dispatched[0].errs) != 1 || !dispatched[0].ended {
+		t.Errorf("got dispatch span %+v", dispatched[0])
+	}
+}
@@ -0,0 +1,186 @@
+package signalmgr
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// A request to the REST API, as passed to a Transport.
+type Request struct {
+	Method string
+	// Absolute URL of the request.
+	URL string
+	// Path and query of the request, relative to the client's URL.
+	Path string
+	// Path with its parameters replaced by placeholders, e.g. "/v1/groups/{number}", for grouping requests by endpoint.
+	Route  string
+	Header http.Header
+	// Body of the request, nil for requests without one.
+	Body []byte
+	// Whether the caller streams the response body, in which case the transport must not buffer it.
+	Stream bool
+}
+
+// A response from the REST API, as returned by a Transport.
+type Response struct {
+	StatusCode int
+	Header     http.Header
+	// Body of the response. The caller closes it.
+	Body io.ReadCloser
+}
+
+// A websocket connection, as returned by a Transport. *websocket.Conn implements it.
+type Conn interface {
+	ReadMessage() (messageType int, p []byte, err error)
+	WriteControl(messageType int, data []byte, deadline time.Time) error
+	SetReadDeadline(t time.Time) error
+	SetPongHandler(h func(appData string) error)
+	Close() error
+}
+
+// A Transport sends requests to the REST API and opens its websockets.
+//
+// Transports return ctx.Err() when ctx is done before the response arrives.
+type Transport interface {
+	// Sends req and returns the response.
+	RoundTrip(ctx context.Context, req *Request) (*Response, error)
+	// Opens a websocket to req.URL, an http or https URL.
+	Dial(ctx context.Context, req *Request) (Conn, error)
+}
+
+// Returns the error for a websocket handshake to req that failed with err: an *APIError with the status and body of resp if the REST API answered with an error, or err otherwise.
+//
+// For Transport implementations, so that websockets report rejected credentials and other API errors like requests do.
+func HandshakeError(req *Request, resp *http.Response, err error) error {
+	if resp == nil {
+		return err
+	}
+	var body []byte
+	if resp.Body != nil {
+		body, _ = io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	}
+	if apiErr := checkResponse(resp.StatusCode, req.Method, req.Path, body); apiErr != nil {
+		return apiErr
+	}
+	return err
+}
+
+// Returns the client's transport.
+func (c *Client) transport() Transport {
+	if w, ok := c.Transport.(transportWrapper); ok {
+		return w.wrap(httpTransport{c})
+	}
+	if c.Transport != nil {
+		return c.Transport
+	}
+	return httpTransport{c}
+}
+
+// Creates a request for method and API URL + path with the client's headers set.
+func (c *Client) newRequest(method, path string, body []byte) *Request {
+	header := http.Header{}
+	for key, val := range c.Headers {
+		header.Set(key, val)
+	}
+	if body != nil {
+		header.Set("Content-Type", "application/json")
+	}
+	return &Request{
+		Method: method,
+		URL:    c.baseURL() + path,
+		Path:   path,
+		Route:  routeTemplate(path),
+		Header: header,
+		Body:   body,
+	}
+}
+
+// Returns the client's HTTPClient, or the client built from its settings on first use.
+func (c *Client) httpClient() *http.Client {
+	if c.HTTPClient != nil {
+		return c.HTTPClient
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.defaultHTTP == nil {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = c.TLSConfig
+		if c.Proxy != nil {
+			transport.Proxy = c.Proxy
+		}
+		if c.UnixSocket != "" {
+			transport.Proxy = nil
+			transport.DialContext = c.dialUnix
+		}
+		c.defaultHTTP = &http.Client{Transport: transport}
+	}
+	return c.defaultHTTP
+}
+
+// Connects to the client's UnixSocket, whatever the address.
+func (c *Client) dialUnix(ctx context.Context, _, _ string) (net.Conn, error) {
+	var d net.Dialer
+	return d.DialContext(ctx, "unix", c.UnixSocket)
+}
+
+// The default Transport, sending requests with net/http and opening websockets with the client's Dialer.
+type httpTransport struct {
+	c *Client
+}
+
+func (t httpTransport) RoundTrip(ctx context.Context, req *Request) (*Response, error) {
+	var body io.Reader
+	if req.Body != nil {
+		body = bytes.NewReader(req.Body)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, req.Method, req.URL, body)
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header = req.Header.Clone()
+	resp, err := t.c.httpClient().Do(httpReq)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, err
+	}
+	return &Response{
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header,
+		Body:       resp.Body,
+	}, nil
+}
+
+// Opens a websocket to req.URL, using the client's dialer, TLS configuration, proxy and Unix socket.
+func (t httpTransport) Dial(ctx context.Context, req *Request) (Conn, error) {
+	url := strings.Replace(req.URL, "https://", "wss://", 1)
+	url = strings.Replace(url, "http://", "ws://", 1)
+
+	dialer := *websocket.DefaultDialer
+	if t.c.Dialer != nil {
+		dialer = *t.c.Dialer
+	}
+	if dialer.TLSClientConfig == nil {
+		dialer.TLSClientConfig = t.c.TLSConfig
+	}
+	if t.c.Proxy != nil && (t.c.Dialer == nil || t.c.Dialer.Proxy == nil) {
+		dialer.Proxy = t.c.Proxy
+	}
+	if t.c.UnixSocket != "" && dialer.NetDialContext == nil && dialer.NetDial == nil {
+		dialer.Proxy = nil
+		dialer.NetDialContext = t.c.dialUnix
+	}
+	conn, resp, err := dialer.DialContext(ctx, url, req.Header)
+	if err != nil {
+		return nil, HandshakeError(req, resp, err)
+	}
+	return conn, nil
+}
@@ -0,0 +1,151 @@
+package signalmgr_test
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/DonovanDiamond/signalmgr"
+	"github.com/DonovanDiamond/signalmgr/signalmgrtest"
+)
+
+// Reads one message delivered by srv from a receive websocket of account.
+func receiveOne(t *testing.T, srv *signalmgrtest.Server, account *signalmgr.Account) signalmgr.MessageResponse {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer func() {
+		// Wait for the websocket to close, so the next one gets the next message.
+		cancel()
+		for srv.Connections(account.Number) != 0 {
+			time.Sleep(5 * time.Millisecond)
+		}
+	}()
+	messages := make(chan signalmgr.MessageResponse)
+	errs := make(chan error, 1)
+	go func() { errs <- account.GetMessagesSocketCtx(ctx, messages) }()
+	for srv.Connections(account.Number) == 0 {
+		select {
+		case err := <-errs:
+			t.Fatalf("dial failed: %v", err)
+		case <-time.After(5 * time.Millisecond):
+		}
+	}
+	srv.Deliver(account.Number, signalmgrtest.TextEnvelope(otherNumber, "hi"))
+	select {
+	case m := <-messages:
+		return m
+	case <-ctx.Done():
+		t.Fatal("no message received")
+		return signalmgr.MessageResponse{}
+	}
+}
+
+func TestUnixSocket(t *testing.T) {
+	srv := signalmgrtest.NewServer(testNumber)
+	defer srv.Close()
+	// Socket paths are limited to about 100 bytes, which t.TempDir may exceed.
+	dir, err := os.MkdirTemp("", "signalmgr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	socket := filepath.Join(dir, "api.sock")
+	ln, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	unix := &http.Server{Handler: srv.Config.Handler}
+	go unix.Serve(ln)
+	defer unix.Close()
+
+	// The host is never resolved, every connection goes to the socket.
+	client := &signalmgr.Client{URL: "http://signal.invalid", UnixSocket: socket}
+	if _, err := client.GetAboutCtx(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if m := receiveOne(t, srv, client.Account(testNumber)); m.Envelope.DataMessage.Message != "hi" {
+		t.Errorf("got %+v", m)
+	}
+
+	// With an HTTPClient, requests use its transport while websockets still use the socket.
+	var requests atomic.Int32
+	client = &signalmgr.Client{URL: "http://signal.invalid", UnixSocket: socket, HTTPClient: &http.Client{
+		Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			requests.Add(1)
+			return (&http.Transport{DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, "unix", socket)
+			}}).RoundTrip(req)
+		}),
+	}}
+	if _, err := client.GetAboutCtx(context.Background()); err != nil || requests.Load() != 1 {
+		t.Errorf("got %d requests through the HTTPClient, %v", requests.Load(), err)
+	}
+	receiveOne(t, srv, client.Account(testNumber))
+}
+
+type roundTripFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// Returns an HTTP proxy forwarding to srv, counting the requests it proxies and the tunnels it opens.
+func newProxy(t *testing.T, srv *signalmgrtest.Server, requests, tunnels *atomic.Int32) *url.URL {
+	t.Helper()
+	proxy := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodConnect {
+			requests.Add(1)
+			srv.Config.Handler.ServeHTTP(w, r)
+			return
+		}
+		tunnels.Add(1)
+		upstream, err := net.Dial("tcp", r.Host)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		conn, buf, err := http.NewResponseController(w).Hijack()
+		if err != nil {
+			upstream.Close()
+			return
+		}
+		go func() {
+			io.Copy(upstream, buf)
+			upstream.Close()
+		}()
+		io.Copy(conn, upstream)
+		conn.Close()
+	})}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go proxy.Serve(ln)
+	t.Cleanup(func() { proxy.Close() })
+	return &url.URL{Scheme: "http", Host: ln.Addr().String()}
+}
+
+func TestProxy(t *testing.T) {
+	srv := signalmgrtest.NewServer(testNumber)
+	defer srv.Close()
+	var requests, tunnels atomic.Int32
+	proxyURL := newProxy(t, srv, &requests, &tunnels)
+
+	client := &signalmgr.Client{URL: srv.URL, Proxy: http.ProxyURL(proxyURL)}
+	if _, err := client.GetAboutCtx(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	receiveOne(t, srv, client.Account(testNumber))
+	if requests.Load() != 1 || tunnels.Load() != 1 {
+		t.Errorf("proxied %d requests and %d websockets, want 1 each", requests.Load(), tunnels.Load())
+	}
+}